@@ -1,3 +1,5 @@
+//go:build tinygo
+
 package main
 
 import (
@@ -8,35 +10,11 @@ import (
 	"github.com/topherCantrell/go-led8key/pkg"
 )
 
-type TinyGoGPIOPin struct {
-	TGpin machine.Pin
-}
-
-func (x TinyGoGPIOPin) Write(state bool) {
-	if state {
-		x.TGpin.High()
-	} else {
-		x.TGpin.Low()
-	}
-}
-
-func (x TinyGoGPIOPin) Read() bool {
-	return x.TGpin.Get()
-}
-
-func (x TinyGoGPIOPin) Input() {
-	x.TGpin.Configure(machine.PinConfig{Mode: machine.PinInput})
-}
-
-func (x TinyGoGPIOPin) Output() {
-	x.TGpin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-}
-
 func main() {
 
-	strobe := TinyGoGPIOPin{machine.GPIO28}
-	clk := TinyGoGPIOPin{TGpin: machine.GPIO26}
-	dio := TinyGoGPIOPin{TGpin: machine.GPIO27}
+	strobe := pkg.TinyGoGPIOPin{TGpin: machine.GPIO28}
+	clk := pkg.TinyGoGPIOPin{TGpin: machine.GPIO26}
+	dio := pkg.TinyGoGPIOPin{TGpin: machine.GPIO27}
 
 	p := pkg.NewDISP16KEY(strobe, clk, dio)
 
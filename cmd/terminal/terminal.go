@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/topherCantrell/go-led8key/pkg"
+)
+
+// Mirror the buttons on the LEDs and show which button (1 to 8) was
+// pressed last. This works the same on the real board. Returns the first
+// error.
+func runLED8KEY(ctx context.Context, p pkg.LED8KEYBoard) error {
+	err := p.ConfigureDisplay(true, 7)
+	if err == nil {
+		err = p.WriteString("HELLo")
+	}
+
+	buttons := [8]bool{}
+	for err == nil && ctx.Err() == nil {
+		err = p.ReadButtons(&buttons)
+		if err == nil {
+			err = p.SetLEDs(buttons)
+		}
+		for i, b := range buttons {
+			if b && err == nil {
+				err = p.WriteString(fmt.Sprintf("%8d", i+1))
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return err
+}
+
+// Show which button (1 to 16) was pressed last. Returns the first error.
+func runDISP16KEY(ctx context.Context, p pkg.DISP16KEYBoard) error {
+	err := p.ConfigureDisplay(true, 7)
+	if err == nil {
+		err = p.WriteString("8.8.8.8.8.8.8.8.")
+	}
+
+	buttons := [16]bool{}
+	for err == nil && ctx.Err() == nil {
+		err = p.ReadButtons(&buttons)
+		for i, b := range buttons {
+			if b && err == nil {
+				err = p.WriteString(fmt.Sprintf("%8d", i+1))
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return err
+}
+
+func main() {
+
+	board := flag.String("board", "led8key", "The board to emulate: led8key or disp16key")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The app stops the terminal if it fails. Its error is printed once
+	// the terminal is out of raw mode.
+	appErr := make(chan error, 1)
+	var t *pkg.Terminal
+	switch *board {
+	case "led8key":
+		p := pkg.NewVirtualLED8KEY()
+		t = pkg.NewTerminal(p.VirtualBoard, os.Stdin, os.Stdout)
+		go func() {
+			appErr <- runLED8KEY(ctx, p)
+			cancel()
+		}()
+	case "disp16key":
+		p := pkg.NewVirtualDISP16KEY()
+		t = pkg.NewTerminal(p.VirtualBoard, os.Stdin, os.Stdout)
+		go func() {
+			appErr <- runDISP16KEY(ctx, p)
+			cancel()
+		}()
+	default:
+		fmt.Println("Unknown board:", *board)
+		os.Exit(1)
+	}
+
+	err := t.Run(ctx)
+	cancel()
+	if aerr := <-appErr; aerr != nil {
+		fmt.Println("Board:", aerr)
+		os.Exit(1)
+	}
+	if err != nil && err != context.Canceled {
+		fmt.Println("Terminal:", err)
+		os.Exit(1)
+	}
+}
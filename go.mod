@@ -1,5 +1,12 @@
 module github.com/topherCantrell/go-led8key
 
-go 1.15
+go 1.21
 
-require github.com/stianeikeland/go-rpio v4.2.0+incompatible
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/stianeikeland/go-rpio v4.2.0+incompatible
+	golang.org/x/term v0.10.0
+	periph.io/x/conn/v3 v3.7.0
+)
+
+require golang.org/x/sys v0.10.0 // indirect
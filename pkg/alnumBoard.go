@@ -0,0 +1,126 @@
+package pkg
+
+import "context"
+
+/*
+	A board of 4 alphanumeric (14- or 16-segment) digits. A digit has more
+	segments than one GRID can drive, so each digit uses two GRIDs: digit 1
+	is on GRID1 and GRID2, digit 2 on GRID3 and GRID4 and so on.
+
+	The glyph's bits (see wideFont.go) map to the segment lines like this:
+
+	  bits 0-7    SEG1-8 of the first GRID   (memory byte 4n)
+	  bits 8-15   SEG1-8 of the second GRID  (memory byte 4n+2)
+	  bit  16     SEG9 of the first GRID     (memory byte 4n+1, bit 0)
+
+	The segments themselves take two RAM bytes per digit, the SEG1-8 bytes
+	of its two GRIDs (a 14-segment digit leaves SEG7 and SEG8 of the second
+	GRID unused). The decimal point is one more segment than two bytes hold
+	for 16 segments, so it is wired to SEG9 of the first GRID for both
+	fonts: a third byte, which only ever has bit 0 set.
+
+    Memory bytes on chip:
+	0    Digit 1 (left most digit) bits 0-7
+	1    Digit 1 decimal point
+	2    Digit 1 bits 8-15
+	3    Unused
+	4    Digit 2 bits 0-7
+	...
+	14   Digit 4 (right most digit) bits 8-15
+	15   Unused
+*/
+
+type AlnumBoard struct {
+	TM1638
+	WideFont
+	Orientation Orientation  // How the board is mounted
+	glyphs      [4]WideGlyph // The logical glyphs last written (see Glyphs)
+}
+
+// Create a new board of 14-segment digits with the given pins. For
+// 16-segment digits, switch the font:
+//
+//	p.WideFont = *pkg.NewWideFont16()
+func NewAlnumBoard(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) *AlnumBoard {
+	ret := &AlnumBoard{}
+	ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
+	ret.WideFont = *NewWideFont14()
+	return ret
+}
+
+// NewAlnumBoard that reports the first error from setting up the lines.
+func NewAlnumBoardE(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) (*AlnumBoard, error) {
+	ret := &AlnumBoard{}
+	err := ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
+	ret.WideFont = *NewWideFont14()
+	return ret, err
+}
+
+// Write 4 glyphs.
+func (x *AlnumBoard) WriteGlyphs(glyphs [4]WideGlyph) error {
+	return x.WriteGlyphsContext(context.Background(), glyphs)
+}
+
+// WriteGlyphs that can be cancelled.
+func (x *AlnumBoard) WriteGlyphsContext(ctx context.Context, glyphs [4]WideGlyph) error {
+	frame := AlnumFrame(x.Orientation.WideGlyphs(x.Segments, glyphs))
+	return x.TransactionContext(ctx, func(bus TM1638Bus) error {
+		err := bus.WriteData(0, frame[:])
+		if err == nil {
+			x.glyphs = glyphs
+		}
+		return err
+	})
+}
+
+// The glyphs last written with WriteGlyphs or WriteString, in logical
+// positions (see LED8KEY.Digits).
+func (x *AlnumBoard) Glyphs() [4]WideGlyph {
+	x.lock()
+	defer x.release()
+	return x.glyphs
+}
+
+// Print the string to the display using the font.
+// This writes from left to right and blanks any unused digits to the right.
+// chars = the text string.
+func (x *AlnumBoard) WriteString(chars string) error {
+	return x.WriteStringContext(context.Background(), chars)
+}
+
+// WriteString that can be cancelled.
+func (x *AlnumBoard) WriteStringContext(ctx context.Context, chars string) error {
+	var glyphs [4]WideGlyph
+	err := x.BuildGlyphs(chars, glyphs[:])
+	if err != nil {
+		return err
+	}
+	return x.WriteGlyphsContext(ctx, glyphs)
+}
+
+// The display RAM for 4 glyphs.
+func AlnumFrame(glyphs [4]WideGlyph) [16]byte {
+	var ret [16]byte
+	for i, g := range glyphs {
+		ret[i*4] = byte(g)
+		ret[i*4+1] = byte(g>>16) & 1
+		ret[i*4+2] = byte(g >> 8)
+	}
+	return ret
+}
+
+// The glyphs in a frame of display RAM. This undoes AlnumFrame.
+func AlnumGlyphs(frame [16]byte) [4]WideGlyph {
+	var ret [4]WideGlyph
+	for i := range ret {
+		ret[i] = WideGlyph(frame[i*4]) | WideGlyph(frame[i*4+2])<<8 | WideGlyph(frame[i*4+1]&1)<<16
+	}
+	return ret
+}
+
+// What the display shows, decoded with the font. This comes from the
+// glyphs last written (see Glyphs).
+func (x *AlnumBoard) DisplayText() string {
+	glyphs := x.Glyphs()
+	return x.DecodeGlyphs(glyphs[:])
+}
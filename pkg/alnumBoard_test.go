@@ -0,0 +1,111 @@
+package pkg
+
+import "testing"
+
+func TestAlnumFrame(t *testing.T) {
+	glyphs := [4]WideGlyph{0x1ABCD, 0x0FFFF, WideSegDP, 0}
+	frame := AlnumFrame(glyphs)
+	want := [16]byte{
+		0xCD, 0x01, 0xAB, 0x00,
+		0xFF, 0x00, 0xFF, 0x00,
+		0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if frame != want {
+		t.Errorf("frame % X, expected % X", frame, want)
+	}
+	if got := AlnumGlyphs(frame); got != glyphs {
+		t.Errorf("glyphs % X, expected % X", got, glyphs)
+	}
+}
+
+func TestAlnumBoardWriteString(t *testing.T) {
+	for _, font := range []*WideFont{NewWideFont14(), NewWideFont16()} {
+		sim := NewTM1638Sim()
+		p := NewAlnumBoard(sim.Pins())
+		p.Timing = TM1638Timing{}
+		p.WideFont = *font
+		if err := p.WriteString("A.5"); err != nil {
+			t.Fatal(err)
+		}
+		want := AlnumFrame([4]WideGlyph{font.font['A'] | WideSegDP, font.font['5']})
+		if d := sim.Display(); d != want {
+			t.Errorf("%d segments: display RAM % X, expected % X", len(font.Segments), d, want)
+		}
+		// The point is on SEG9 of the first GRID for both fonts
+		if d := sim.Display(); d[1] != 0x01 || d[5] != 0x00 {
+			t.Errorf("%d segments: points % X % X", len(font.Segments), d[1], d[5])
+		}
+		if got := p.DisplayText(); got != "A.5  " {
+			t.Errorf("%d segments: display text %q", len(font.Segments), got)
+		}
+	}
+}
+
+func TestOrientationWideGlyph(t *testing.T) {
+	tests := []struct {
+		o    Orientation
+		set  []string
+		in   string
+		want string
+	}{
+		{OrientationNormal, Segments14, "a g1 h dp", "a g1 h dp"},
+		{OrientationRotate180, Segments14, "a g1 h dp", "d g2 m dp"},
+		{OrientationMirrorX, Segments14, "b g1 h i", "f g2 j i"},
+		{OrientationMirrorY, Segments14, "a b h i", "d c k l"},
+		{OrientationRotate180, Segments16, "a1 d1 j", "d2 a2 k"},
+		{OrientationMirrorX, Segments16, "a1 d1 m", "a2 d2 k"},
+		{OrientationMirrorY, Segments16, "a1 a2 g1", "d1 d2 g1"},
+	}
+	for _, test := range tests {
+		in, want := MustWideSegments(test.set, test.in), MustWideSegments(test.set, test.want)
+		if got := test.o.WideGlyph(test.set, in); got != want {
+			t.Errorf("%v turns %s into %v, expected %s", test.o, test.in, WideSegmentNames(test.set, got), test.want)
+		}
+		if got := test.o.WideGlyph(test.set, want); got != in {
+			t.Errorf("%v turns %s back into %v, expected %s", test.o, test.want, WideSegmentNames(test.set, got), test.in)
+		}
+	}
+}
+
+// Upside down, the text still reads the right way and the points stay
+// after their characters.
+func TestAlnumBoardOrientation(t *testing.T) {
+	for o := OrientationNormal; o <= OrientationMirrorY; o++ {
+		sim := NewTM1638Sim()
+		p := NewAlnumBoard(sim.Pins())
+		p.Timing = TM1638Timing{}
+		p.Orientation = o
+		if err := p.WriteString("1.2.3."); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.DisplayText(); got != "1.2.3. " {
+			t.Errorf("%v display text %q", o, got)
+		}
+	}
+
+	sim := NewTM1638Sim()
+	p := NewAlnumBoard(sim.Pins())
+	p.Timing = TM1638Timing{}
+	p.Orientation = OrientationRotate180
+	if err := p.WriteString("T."); err != nil {
+		t.Fatal(err)
+	}
+	// The T is upside down in the rightmost digit, its point on the one to
+	// its left
+	want := AlnumFrame([4]WideGlyph{2: WideSegDP, 3: MustWideSegments(Segments14, "d i l")})
+	if d := sim.Display(); d != want {
+		t.Errorf("display RAM % X, expected % X", d, want)
+	}
+
+	// The last point has no digit to draw it, but DisplayText still has it
+	if err := p.WriteString("ABCD."); err != nil {
+		t.Fatal(err)
+	}
+	if d := sim.Display(); AlnumGlyphs(d)[0]&WideSegDP != 0 {
+		t.Errorf("stray point in % X", d)
+	}
+	if got := p.DisplayText(); got != "ABCD." {
+		t.Errorf("display text %q", got)
+	}
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import "fmt"
+
+// The high-level API of the LED8KEY. Write apps against this and they run
+// on the real board (*LED8KEY) or a virtual one (*VirtualLED8KEY).
+type LED8KEYBoard interface {
+	ConfigureDisplay(enabled bool, pulseWidth int) error
+	WriteDigits(digits [8]byte) error
+	WriteString(chars string) error
+	SetLEDs(leds [8]bool) error
+	ReadButtons(buttons *[8]bool) error
+}
+
+// The high-level API of the DISP16KEY. Implemented by *DISP16KEY and
+// *VirtualDISP16KEY.
+type DISP16KEYBoard interface {
+	ConfigureDisplay(enabled bool, pulseWidth int) error
+	WriteDigits(digits [8]byte) error
+	WriteString(chars string) error
+	ReadButtons(buttons *[16]bool) error
+}
+
+// How a board lays out its digits (and LEDs) in the chip's 16 bytes of
+// display RAM. See the top of led8key.go and disp16key.go.
+type BoardLayout int
+
+const (
+	LayoutLED8KEY   BoardLayout = iota // One byte per digit, LEDs between
+	LayoutDISP16KEY                    // One byte per segment, one bit per digit
+)
+
+func (l BoardLayout) String() string {
+	switch l {
+	case LayoutLED8KEY:
+		return "LED8KEY"
+	case LayoutDISP16KEY:
+		return "DISP16KEY"
+	}
+	return fmt.Sprintf("BoardLayout(%d)", int(l))
+}
+
+// The number of buttons on the board.
+func (l BoardLayout) NumButtons() int {
+	if l == LayoutDISP16KEY {
+		return 16
+	}
+	return 8
+}
+
+// True if the board has the row of 8 LEDs.
+func (l BoardLayout) HasLEDs() bool {
+	return l == LayoutLED8KEY
+}
+
+// Put the digits (xgfedcba, left to right) into a frame. The LED bytes of
+// the frame are left alone.
+func (l BoardLayout) SetDigits(frame *[16]byte, digits [8]byte) {
+	if l == LayoutDISP16KEY {
+		segments := convertEightKeyDigits(digits)
+		for i := range segments {
+			frame[i*2] = segments[i]
+		}
+		return
+	}
+	for i := range digits {
+		frame[i*2] = digits[i]
+	}
+}
+
+// Pull the digits (xgfedcba, left to right) out of a frame.
+func (l BoardLayout) Digits(frame [16]byte) [8]byte {
+	var ret [8]byte
+	if l == LayoutDISP16KEY {
+		for seg := 0; seg < 8; seg++ {
+			for i := 0; i < 8; i++ {
+				if frame[seg*2]&(128>>uint(i)) != 0 {
+					ret[i] |= 1 << uint(seg)
+				}
+			}
+		}
+		return ret
+	}
+	for i := range ret {
+		ret[i] = frame[i*2]
+	}
+	return ret
+}
+
+// Put the LEDs (left to right) into a frame. Does nothing for boards
+// without LEDs.
+func (l BoardLayout) SetLEDs(frame *[16]byte, leds [8]bool) {
+	if !l.HasLEDs() {
+		return
+	}
+	for i := range leds {
+		frame[i*2+1] = 0
+		if leds[i] {
+			frame[i*2+1] = 1
+		}
+	}
+}
+
+// Pull the LEDs (left to right) out of a frame.
+func (l BoardLayout) LEDs(frame [16]byte) [8]bool {
+	var ret [8]bool
+	if !l.HasLEDs() {
+		return ret
+	}
+	for i := range ret {
+		ret[i] = frame[i*2+1]&1 != 0
+	}
+	return ret
+}
+
+var (
+	_ LED8KEYBoard   = (*LED8KEY)(nil)
+	_ LED8KEYBoard   = (*VirtualLED8KEY)(nil)
+	_ DISP16KEYBoard = (*DISP16KEY)(nil)
+	_ DISP16KEYBoard = (*VirtualDISP16KEY)(nil)
+)
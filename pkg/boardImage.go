@@ -0,0 +1,267 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+/*
+	Draws a board state as a picture of the board: an SVG document or a PNG
+	image. Both come from the same list of shapes, so they look the same.
+
+	The drawing is 400 units wide. At Scale 1 one unit is one pixel. Each
+	digit is a 40x70 cell:
+
+	   aaa
+	  f   b
+	  f   b
+	   ggg
+	  e   c
+	  e   c
+	   ddd  x
+
+	Lit segments are drawn in Color mixed with the display background by
+	the pulse width (brightness). A disabled display is all unlit.
+*/
+
+type BoardRenderer struct {
+	Color color.RGBA // Lit segment colour. The zero value means red.
+	Scale float64    // Pixels per unit. Zero means 1.
+}
+
+// The default lit segment colour
+var boardRendererRed = color.RGBA{255, 40, 20, 255}
+
+var (
+	boardRendererPCB     = color.RGBA{0x1a, 0x5c, 0x2a, 255}
+	boardRendererDisplay = color.RGBA{0x11, 0x11, 0x11, 255}
+	boardRendererButton  = color.RGBA{0x22, 0x22, 0x22, 255}
+	boardRendererPressed = color.RGBA{0x66, 0x66, 0x66, 255}
+)
+
+// Segment outlines in a digit cell (xgfedcba: a is bit 0)
+var boardRendererSegments = [7][]boardPoint{
+	{{8, 4}, {32, 4}, {28, 9}, {12, 9}},                         // a
+	{{33, 5}, {33, 33}, {29, 30}, {29, 10}},                     // b
+	{{33, 37}, {33, 65}, {29, 60}, {29, 40}},                    // c
+	{{8, 66}, {32, 66}, {28, 61}, {12, 61}},                     // d
+	{{7, 37}, {7, 65}, {11, 60}, {11, 40}},                      // e
+	{{7, 5}, {7, 33}, {11, 30}, {11, 10}},                       // f
+	{{9, 35}, {13, 32}, {27, 32}, {31, 35}, {27, 38}, {13, 38}}, // g
+}
+
+type boardPoint struct {
+	X, Y float64
+}
+
+type boardShapeKind int
+
+const (
+	boardRect    boardShapeKind = iota // Points[0] is the corner, Points[1] the size
+	boardCircle                        // Points[0] is the centre, R the radius
+	boardPolygon                       // Points is the outline
+)
+
+type boardShape struct {
+	Kind   boardShapeKind
+	Points []boardPoint
+	R      float64
+	Fill   color.RGBA
+	Button int // For a button, 1 + its index. 0 for everything else.
+}
+
+// The size of the drawing in units.
+func boardRendererSize(layout BoardLayout) (width float64, height float64) {
+	height = 100
+	if layout.HasLEDs() {
+		height += 40
+	}
+	perRow := 8
+	if layout == LayoutDISP16KEY {
+		perRow = 4
+	}
+	height += float64(layout.NumButtons()/perRow) * 50
+	return 8*48 + 16, height
+}
+
+// The shapes of the board, back to front.
+func (r BoardRenderer) shapes(state BoardState) []boardShape {
+	lit := r.Color
+	if lit == (color.RGBA{}) {
+		lit = boardRendererRed
+	}
+	unlit := boardRendererMix(boardRendererDisplay, lit, 0.15)
+	lit = boardRendererMix(unlit, lit, 0.35+float64(state.PulseWidth&7)*0.65/7)
+	fill := func(on bool) color.RGBA {
+		if on && state.Enabled {
+			return lit
+		}
+		return unlit
+	}
+
+	width, height := boardRendererSize(state.Layout)
+	ret := []boardShape{
+		{Kind: boardRect, Points: []boardPoint{{0, 0}, {width, height}}, Fill: boardRendererPCB},
+		{Kind: boardRect, Points: []boardPoint{{8, 8}, {width - 16, 84}}, Fill: boardRendererDisplay},
+	}
+
+	digits := state.Digits()
+	for d, digit := range digits {
+		ox := float64(14 + d*48)
+		oy := 15.0
+		for s, outline := range boardRendererSegments {
+			points := make([]boardPoint, len(outline))
+			for i, p := range outline {
+				points[i] = boardPoint{p.X + ox, p.Y + oy}
+			}
+			ret = append(ret, boardShape{Kind: boardPolygon, Points: points, Fill: fill(digit&(1<<uint(s)) != 0)})
+		}
+		ret = append(ret, boardShape{Kind: boardCircle, Points: []boardPoint{{ox + 38, oy + 65}}, R: 3, Fill: fill(digit&0x80 != 0)})
+	}
+
+	y := 100.0
+	if state.Layout.HasLEDs() {
+		leds := state.LEDs()
+		for i, led := range leds {
+			ret = append(ret, boardShape{Kind: boardCircle, Points: []boardPoint{{float64(34 + i*48), y + 16}}, R: 8, Fill: fill(led)})
+		}
+		y += 40
+	}
+
+	perRow := 8
+	if state.Layout == LayoutDISP16KEY {
+		perRow = 4
+	}
+	pitch := (width - 16) / float64(perRow)
+	for i := 0; i < state.Layout.NumButtons(); i++ {
+		c := boardPoint{8 + pitch*(float64(i%perRow)+0.5), y + 25 + float64(i/perRow)*50}
+		f := boardRendererButton
+		if state.Buttons[i] {
+			f = boardRendererPressed
+		}
+		ret = append(ret, boardShape{Kind: boardCircle, Points: []boardPoint{c}, R: 16, Fill: f, Button: i + 1})
+	}
+	return ret
+}
+
+func (r BoardRenderer) scale() float64 {
+	if r.Scale <= 0 {
+		return 1
+	}
+	return r.Scale
+}
+
+// Write the board as an SVG document.
+func (r BoardRenderer) SVG(w io.Writer, state BoardState) error {
+	scale := r.scale()
+	width, height := boardRendererSize(state.Layout)
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		width*scale, height*scale, width, height)
+	for _, s := range r.shapes(state) {
+		fill := fmt.Sprintf("#%02x%02x%02x", s.Fill.R, s.Fill.G, s.Fill.B)
+		switch s.Kind {
+		case boardRect:
+			fmt.Fprintf(b, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n",
+				s.Points[0].X, s.Points[0].Y, s.Points[1].X, s.Points[1].Y, fill)
+		case boardCircle:
+			fmt.Fprintf(b, `<circle cx="%g" cy="%g" r="%g" fill="%s"`, s.Points[0].X, s.Points[0].Y, s.R, fill)
+			if s.Button > 0 {
+				// The web board page finds the buttons by this
+				fmt.Fprintf(b, ` class="button" data-button="%d"`, s.Button-1)
+			}
+			b.WriteString("/>\n")
+		case boardPolygon:
+			b.WriteString(`<polygon points="`)
+			for i, p := range s.Points {
+				if i > 0 {
+					b.WriteString(" ")
+				}
+				fmt.Fprintf(b, "%g,%g", p.X, p.Y)
+			}
+			fmt.Fprintf(b, `" fill="%s"/>`+"\n", fill)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
+
+// Draw the board into an image.
+func (r BoardRenderer) Image(state BoardState) *image.RGBA {
+	scale := r.scale()
+	width, height := boardRendererSize(state.Layout)
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(width*scale)), int(math.Ceil(height*scale))))
+	for _, s := range r.shapes(state) {
+		// The pixels to look at
+		min := boardPoint{math.Inf(1), math.Inf(1)}
+		max := boardPoint{math.Inf(-1), math.Inf(-1)}
+		switch s.Kind {
+		case boardRect:
+			min = s.Points[0]
+			max = boardPoint{s.Points[0].X + s.Points[1].X, s.Points[0].Y + s.Points[1].Y}
+		case boardCircle:
+			min = boardPoint{s.Points[0].X - s.R, s.Points[0].Y - s.R}
+			max = boardPoint{s.Points[0].X + s.R, s.Points[0].Y + s.R}
+		case boardPolygon:
+			for _, p := range s.Points {
+				min = boardPoint{math.Min(min.X, p.X), math.Min(min.Y, p.Y)}
+				max = boardPoint{math.Max(max.X, p.X), math.Max(max.Y, p.Y)}
+			}
+		}
+		x0, y0 := int(math.Floor(min.X*scale)), int(math.Floor(min.Y*scale))
+		x1, y1 := int(math.Ceil(max.X*scale)), int(math.Ceil(max.Y*scale))
+		for py := y0; py < y1; py++ {
+			for px := x0; px < x1; px++ {
+				// The centre of the pixel in units
+				p := boardPoint{(float64(px) + 0.5) / scale, (float64(py) + 0.5) / scale}
+				if s.contains(p) {
+					img.SetRGBA(px, py, s.Fill)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Write the board as a PNG image.
+func (r BoardRenderer) PNG(w io.Writer, state BoardState) error {
+	return png.Encode(w, r.Image(state))
+}
+
+// True if the point is inside the shape.
+func (s boardShape) contains(p boardPoint) bool {
+	switch s.Kind {
+	case boardRect:
+		return p.X >= s.Points[0].X && p.X < s.Points[0].X+s.Points[1].X &&
+			p.Y >= s.Points[0].Y && p.Y < s.Points[0].Y+s.Points[1].Y
+	case boardCircle:
+		dx, dy := p.X-s.Points[0].X, p.Y-s.Points[0].Y
+		return dx*dx+dy*dy <= s.R*s.R
+	}
+	// Count the edges crossed by a ray to the right
+	in := false
+	j := len(s.Points) - 1
+	for i := range s.Points {
+		a, b := s.Points[i], s.Points[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+		j = i
+	}
+	return in
+}
+
+// Mix from a to b by t (0 is all a, 1 is all b).
+func boardRendererMix(a color.RGBA, b color.RGBA, t float64) color.RGBA {
+	mix := func(x uint8, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
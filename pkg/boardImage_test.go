@@ -0,0 +1,109 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// "3.14" with LEDs 1 and 3 on and button 3 held (the led8key replay
+// fixture's RAM).
+var rendererState = BoardState{
+	Layout: LayoutLED8KEY,
+	Frame: [16]byte{
+		0xCF, 0x01, 0x06, 0x00, 0x66, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	Enabled:    true,
+	PulseWidth: 7,
+	Buttons:    [16]bool{2: true},
+}
+
+// The SVG must match testdata/led8key.svg. Run with TM1638_UPDATE=1 to
+// write it again (and look at it before checking it in).
+func TestBoardRendererSVG(t *testing.T) {
+	var svg bytes.Buffer
+	if err := (BoardRenderer{}).SVG(&svg, rendererState); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join("testdata", "led8key.svg")
+	if os.Getenv("TM1638_UPDATE") != "" {
+		if err := os.WriteFile(name, svg.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := readReplayFixture(t, "led8key.svg"); !bytes.Equal(svg.Bytes(), want) {
+		t.Errorf("SVG doesn't match %s:\n%s", name, svg.Bytes())
+	}
+}
+
+// Spot checks of the pixels. At full brightness a lit segment is exactly
+// the lit colour. Unlit is 15% of the way from the display to it.
+func TestBoardRendererPNG(t *testing.T) {
+	red := color.RGBA{255, 40, 20, 255}
+	unlit := color.RGBA{53, 20, 17, 255}
+	tests := []struct {
+		name  string
+		x, y  int
+		color color.RGBA
+	}{
+		{"PCB", 2, 2, color.RGBA{0x1a, 0x5c, 0x2a, 255}},
+		{"display", 12, 12, color.RGBA{0x11, 0x11, 0x11, 255}},
+		{"digit 0 segment a", 34, 21, red},
+		{"digit 0 segment g", 34, 50, red},
+		{"digit 0 segment e", 23, 66, unlit},
+		{"digit 0 point", 52, 80, red},
+		{"digit 1 segment a", 82, 21, unlit},
+		{"digit 1 segment c", 93, 66, red},
+		{"digit 1 point", 100, 80, unlit},
+		{"LED 1", 34, 116, red},
+		{"LED 2", 82, 116, unlit},
+		{"button 1", 32, 165, color.RGBA{0x22, 0x22, 0x22, 255}},
+		{"button 3", 128, 165, color.RGBA{0x66, 0x66, 0x66, 255}},
+	}
+
+	var b bytes.Buffer
+	if err := (BoardRenderer{}).PNG(&b, rendererState); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 400 || size.Y != 190 {
+		t.Errorf("size %v", size)
+	}
+	for _, test := range tests {
+		got := color.RGBAModel.Convert(img.At(test.x, test.y))
+		if got != test.color {
+			t.Errorf("%s at (%d,%d): %v, expected %v", test.name, test.x, test.y, got, test.color)
+		}
+	}
+
+	// Everything is unlit with the display off
+	off := rendererState
+	off.Enabled = false
+	dark := (BoardRenderer{}).Image(off)
+	for _, test := range tests {
+		want := test.color
+		if want == red {
+			want = unlit
+		}
+		if got := dark.RGBAAt(test.x, test.y); got != want {
+			t.Errorf("display off: %s at (%d,%d): %v, expected %v", test.name, test.x, test.y, got, want)
+		}
+	}
+
+	// Scale 2 draws the same thing twice the size
+	big := BoardRenderer{Scale: 2}.Image(rendererState)
+	for _, test := range tests {
+		if got := big.RGBAAt(test.x*2, test.y*2); got != test.color {
+			t.Errorf("scale 2: %s at (%d,%d): %v, expected %v", test.name, test.x*2, test.y*2, got, test.color)
+		}
+	}
+}
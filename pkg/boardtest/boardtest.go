@@ -0,0 +1,247 @@
+// Helpers for testing apps written against the LED8KEY and DISP16KEY. The
+// board runs the real driver against a simulated chip, and the helpers
+// check what the chip ends up showing: the text (decoded back from the
+// segments with the board's font), the LEDs and the brightness. The text
+// and digits come from the digits the driver last wrote (in the logical
+// positions the app uses), after checking that the chip shows them.
+//
+//	func TestHello(t *testing.T) {
+//		p, b := boardtest.NewLED8KEY(t)
+//		showHello(p)
+//		b.ExpectText("HELLo")
+//		b.ExpectLEDs([8]bool{true})
+//		b.ExpectGolden("hello")
+//	}
+//
+// Golden files live in testdata/NAME.golden. Run the tests with
+// BOARDTEST_UPDATE=1 to (re)write them.
+package boardtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/topherCantrell/go-led8key/pkg"
+)
+
+// A simulated board and its test.
+type Board struct {
+	Sim    *pkg.TM1638Sim
+	Layout pkg.BoardLayout
+	Font   *pkg.SevenSegFont // Decodes the text. This is the board's own font.
+	Dir    string            // Where the golden files live
+
+	orientation *pkg.Orientation // The board's, to undo
+	digits      func() [8]byte   // The driver's logical digits
+	t           testing.TB
+}
+
+// What the display shows, in the logical positions the app uses (the
+// board's Orientation undone).
+type Screen struct {
+	Text       string // Decoded with the font. '?' for unknown patterns.
+	Digits     [8]byte
+	LEDs       [8]bool
+	Enabled    bool
+	PulseWidth int
+}
+
+// Create an LED8KEY on a simulated chip.
+func NewLED8KEY(t testing.TB) (*pkg.LED8KEY, *Board) {
+	sim := pkg.NewTM1638Sim()
+	p := pkg.NewLED8KEY(sim.Pins())
+	p.Timing = pkg.TM1638Timing{} // The simulator needs no delays
+	return p, &Board{sim, pkg.LayoutLED8KEY, &p.SevenSegFont, "testdata", &p.Orientation, p.Digits, t}
+}
+
+// Create a DISP16KEY on a simulated chip.
+func NewDISP16KEY(t testing.TB) (*pkg.DISP16KEY, *Board) {
+	sim := pkg.NewTM1638Sim()
+	p := pkg.NewDISP16KEY(sim.Pins())
+	p.Timing = pkg.TM1638Timing{}
+	return p, &Board{sim, pkg.LayoutDISP16KEY, &p.SevenSegFont, "testdata", &p.Orientation, p.Digits, t}
+}
+
+// Press or release a button (numbered like ReadButtons).
+func (b *Board) SetButton(button int, pressed bool) {
+	b.t.Helper()
+	keys := pkg.LED8KEYKeys[:]
+	if b.Layout == pkg.LayoutDISP16KEY {
+		keys = pkg.DISP16KEYKeys[:]
+	}
+	if button < 0 || button >= len(keys) {
+		b.t.Fatalf("No button %d on the %v", button, b.Layout)
+	}
+	b.Sim.SetKey(keys[b.orientation.Button(b.Layout, button)], pressed)
+}
+
+// Release all the buttons.
+func (b *Board) ReleaseButtons() {
+	b.Sim.ReleaseKeys()
+}
+
+// What the chip is showing now. The digits are the ones the driver last
+// wrote. If the chip shows something else, the test fails.
+func (b *Board) Screen() Screen {
+	b.t.Helper()
+	frame := b.Sim.Display()
+	enabled, pulseWidth := b.Sim.DisplayControl()
+	digits := b.digits()
+	physical := b.Layout.Digits(frame)
+	if want := b.orientation.PhysicalDigits(digits); physical != want {
+		b.t.Errorf("chip shows digits % X, but the driver wrote % X", physical, want)
+	}
+	return Screen{
+		Text:       decodeText(b.Font, digits),
+		Digits:     digits,
+		LEDs:       b.orientation.LEDs(b.Layout.LEDs(frame)),
+		Enabled:    enabled,
+		PulseWidth: pulseWidth,
+	}
+}
+
+// Check the text. Trailing blanks are ignored.
+func (b *Board) ExpectText(want string) {
+	b.t.Helper()
+	got := b.Screen().Text
+	if got != strings.TrimRight(want, " ") {
+		b.t.Errorf("display text is %q, expected %q", got, want)
+	}
+}
+
+// Check the LEDs.
+func (b *Board) ExpectLEDs(want [8]bool) {
+	b.t.Helper()
+	got := b.Screen().LEDs
+	if got != want {
+		b.t.Errorf("LEDs are %s, expected %s", ledString(got), ledString(want))
+	}
+}
+
+// Check the display control.
+func (b *Board) ExpectBrightness(enabled bool, pulseWidth int) {
+	b.t.Helper()
+	s := b.Screen()
+	if s.Enabled != enabled || s.PulseWidth != pulseWidth {
+		b.t.Errorf("display is %s, expected %s", onString(s.Enabled, s.PulseWidth), onString(enabled, pulseWidth))
+	}
+}
+
+// Check everything on the screen, digits included (all zero for a blank
+// display). Use ExpectText, ExpectLEDs and ExpectBrightness to check only
+// some of it.
+func (b *Board) ExpectScreen(want Screen) {
+	b.t.Helper()
+	got := b.Screen()
+	want.Text = strings.TrimRight(want.Text, " ")
+	if got != want {
+		b.t.Errorf("screen differs (-expected +got):\n%s", Diff(want.String(), got.String()))
+	}
+}
+
+// Check the screen against testdata/NAME.golden. With BOARDTEST_UPDATE=1
+// the golden file is written instead.
+func (b *Board) ExpectGolden(name string) {
+	b.t.Helper()
+	got := b.Screen().String()
+	path := filepath.Join(b.Dir, name+".golden")
+	if os.Getenv("BOARDTEST_UPDATE") != "" {
+		err := os.MkdirAll(b.Dir, 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(got), 0644)
+		}
+		if err != nil {
+			b.t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		b.t.Fatalf("reading golden file (run with BOARDTEST_UPDATE=1 to create it): %v", err)
+	}
+	if string(want) != got {
+		b.t.Errorf("screen differs from %s (-golden +got):\n%s", path, Diff(string(want), got))
+	}
+}
+
+// The screen as text, one field per line. This is the golden file format.
+func (s Screen) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "text:    %q\n", s.Text)
+	fmt.Fprintf(&b, "digits: ")
+	for _, d := range s.Digits {
+		fmt.Fprintf(&b, " %02X", d)
+	}
+	fmt.Fprintf(&b, "\nleds:    %s\n", ledString(s.LEDs))
+	fmt.Fprintf(&b, "display: %s\n", onString(s.Enabled, s.PulseWidth))
+	return b.String()
+}
+
+// Compare two texts line by line. Lines only in want start with "-", lines
+// only in got with "+" and matching lines with " ".
+func Diff(want string, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// Longest common subsequence, filled from the ends
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ret strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ret.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ret.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			ret.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return ret.String()
+}
+
+// Turn the digits back into text. Trailing blanks are dropped.
+func decodeText(font *pkg.SevenSegFont, digits [8]byte) string {
+	return strings.TrimRight(font.DecodeDigits(digits[:]), " ")
+}
+
+func ledString(leds [8]bool) string {
+	ret := ""
+	for _, led := range leds {
+		if led {
+			ret += "*"
+		} else {
+			ret += "."
+		}
+	}
+	return ret
+}
+
+func onString(enabled bool, pulseWidth int) string {
+	if enabled {
+		return fmt.Sprintf("on, pulse width %d", pulseWidth)
+	}
+	return fmt.Sprintf("off, pulse width %d", pulseWidth)
+}
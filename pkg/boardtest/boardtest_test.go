@@ -0,0 +1,181 @@
+package boardtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/topherCantrell/go-led8key/pkg"
+)
+
+// A testing.TB that collects failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func expectFailure(t *testing.T, r *recordingTB, want string) {
+	t.Helper()
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], want) {
+		t.Errorf("failures %q, expected one containing %q", r.failures, want)
+	}
+	r.failures = nil
+}
+
+func TestExpectText(t *testing.T) {
+	p, b := NewLED8KEY(t)
+	if err := p.WriteString("HELLo"); err != nil {
+		t.Fatal(err)
+	}
+	b.ExpectText("HELLo")
+	b.ExpectText("HELLo   ") // Trailing blanks don't matter
+
+	r := &recordingTB{}
+	b.t = r
+	b.ExpectText("12.34")
+	expectFailure(t, r, `display text is "HELLo", expected "12.34"`)
+}
+
+func TestExpectTextRotated(t *testing.T) {
+	p, b := NewDISP16KEY(t)
+	p.Orientation = pkg.OrientationRotate180
+	if err := p.WriteString("12.34"); err != nil {
+		t.Fatal(err)
+	}
+	b.ExpectText("12.34")
+
+	// The chip can't show the last point upside down, but the driver still
+	// has it
+	if err := p.WriteString("1234567.8."); err != nil {
+		t.Fatal(err)
+	}
+	b.ExpectText("1234567.8.")
+}
+
+// The chip must show what the driver wrote.
+func TestScreenChecksChip(t *testing.T) {
+	p, b := NewLED8KEY(t)
+	if err := p.WriteString("12"); err != nil {
+		t.Fatal(err)
+	}
+	b.Sim.PowerCycle()
+	r := &recordingTB{}
+	b.t = r
+	b.Screen()
+	expectFailure(t, r, "chip shows digits")
+	if err := p.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	b.ExpectText("12")
+	if len(r.failures) != 0 {
+		t.Errorf("failures after Restore: %q", r.failures)
+	}
+}
+
+func TestExpectLEDs(t *testing.T) {
+	p, b := NewLED8KEY(t)
+	leds := [8]bool{true, false, true}
+	if err := p.SetLEDs(leds); err != nil {
+		t.Fatal(err)
+	}
+	b.ExpectLEDs(leds)
+
+	r := &recordingTB{}
+	b.t = r
+	b.ExpectLEDs([8]bool{})
+	expectFailure(t, r, "LEDs are *.*....., expected ........")
+}
+
+func TestExpectScreen(t *testing.T) {
+	p, b := NewLED8KEY(t)
+	if err := p.ConfigureDisplay(true, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteString("12"); err != nil {
+		t.Fatal(err)
+	}
+	want := Screen{
+		Text:       "12",
+		Digits:     [8]byte{pkg.MustSegments("bc"), pkg.MustSegments("abdeg")},
+		Enabled:    true,
+		PulseWidth: 4,
+	}
+	b.ExpectScreen(want)
+
+	// The digits are always checked: all zero expects a blank display
+	r := &recordingTB{}
+	b.t = r
+	want.Digits = [8]byte{}
+	b.ExpectScreen(want)
+	expectFailure(t, r, "- digits:  00 00")
+}
+
+func TestExpectGolden(t *testing.T) {
+	p, b := NewLED8KEY(t)
+	b.Dir = t.TempDir()
+	if err := p.WriteString("42"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BOARDTEST_UPDATE", "1")
+	b.ExpectGolden("answer")
+	golden, err := os.ReadFile(filepath.Join(b.Dir, "answer.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(golden) != b.Screen().String() {
+		t.Errorf("golden file is\n%s", golden)
+	}
+
+	t.Setenv("BOARDTEST_UPDATE", "")
+	b.ExpectGolden("answer")
+	if err := p.WriteString("43"); err != nil {
+		t.Fatal(err)
+	}
+	r := &recordingTB{}
+	b.t = r
+	b.ExpectGolden("answer")
+	expectFailure(t, r, `+ text:    "43"`)
+}
+
+func TestSetButton(t *testing.T) {
+	p, b := NewDISP16KEY(t)
+	p.Orientation = pkg.OrientationMirrorX
+	b.SetButton(5, true)
+	var buttons [16]bool
+	if err := p.ReadButtons(&buttons); err != nil {
+		t.Fatal(err)
+	}
+	for i, pressed := range buttons {
+		if pressed != (i == 5) {
+			t.Errorf("button %d pressed: %v", i, pressed)
+		}
+	}
+	b.ReleaseButtons()
+	if err := p.ReadButtons(&buttons); err != nil {
+		t.Fatal(err)
+	}
+	if buttons != ([16]bool{}) {
+		t.Errorf("buttons still pressed: %v", buttons)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\n", "a\nx\nc\n")
+	want := "  a\n- b\n+ x\n  c\n"
+	if got != want {
+		t.Errorf("Diff is\n%s\nexpected\n%s", got, want)
+	}
+}
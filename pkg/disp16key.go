@@ -1,7 +1,7 @@
 package pkg
 
 import (
-	"fmt"
+	"context"
 )
 
 /*
@@ -49,87 +49,129 @@ import (
 	bit 7: far left digit
 
 	All four of the read-scanning-keys bytes are used to capture the 16 buttons
-	on the board:
+	on the board. Rows 1 and 2 are on K1. Rows 3 and 4 are on K2 (see
+	tm1638Keys.go for the datasheet's K/SEG layout).
 
 	// Returns from pressing each button one at a time
 	//     Column 1       Column 2       Column 3       Column 4
-	//  [20,00,00,00]  [02,00,00,00]  [00,20,00,00]  [00,02,00,00] Row 1
-	//  [00,00,20,00]  [00,00,02,00]  [00,00,00,20]  [00,00,00,02] Row 2
-	//  [40,00,00,00]  [04,00,00,00]  [00,40,00,00]  [00,04,00,00] Row 3
-	//  [00,00,40,00]  [00,00,04,00]  [00,00,00,40]  [00,00,00,04] Row 4
+	//  [04,00,00,00]  [40,00,00,00]  [00,04,00,00]  [00,40,00,00] Row 1 (K1/SEG1-4)
+	//  [00,00,04,00]  [00,00,40,00]  [00,00,00,04]  [00,00,00,40] Row 2 (K1/SEG5-8)
+	//  [02,00,00,00]  [20,00,00,00]  [00,02,00,00]  [00,20,00,00] Row 3 (K2/SEG1-4)
+	//  [00,00,02,00]  [00,00,20,00]  [00,00,00,02]  [00,00,00,20] Row 4 (K2/SEG5-8)
 
 */
 
+// Where the buttons (left to right, top to bottom) are in the TM1638's
+// key matrix.
+var DISP16KEYKeys = [16]KeyPosition{
+	{1, 1}, {1, 2}, {1, 3}, {1, 4},
+	{1, 5}, {1, 6}, {1, 7}, {1, 8},
+	{2, 1}, {2, 2}, {2, 3}, {2, 4},
+	{2, 5}, {2, 6}, {2, 7}, {2, 8},
+}
+
 type DISP16KEY struct {
 	TM1638
 	SevenSegFont
-	digitBuffer [8]byte
+	Orientation Orientation // How the board is mounted
+	digits      [8]byte     // The logical digits last written (see Digits)
 }
 
 // Create a new DISP16KEY driver with the given pin numbers. These numbers
 // are the RPi's BCM pin numbers -- not the board pin numbers on the IO header.
 // See the "What do these numbers mean?" section here: https://pinout.xyz/
-func NewDISP16KEY(pinSTROBE GPIOPin, pinCLK GPIOPin, pinDIO GPIOPin) *DISP16KEY {
+func NewDISP16KEY(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) *DISP16KEY {
 	ret := &DISP16KEY{}
-	ret.TM1638 = *NewTM1638(pinSTROBE, pinCLK, pinDIO)
+	ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
 	ret.ResetFont()
 	return ret
 }
 
+// NewDISP16KEY that reports the first error from setting up the lines.
+func NewDISP16KEYE(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) (*DISP16KEY, error) {
+	ret := &DISP16KEY{}
+	err := ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
+	ret.ResetFont()
+	return ret, err
+}
+
 // Write 8 display digits.
 // digits = array of raw bit patterns for each display
 func (x *DISP16KEY) WriteDigits(digits [8]byte) error {
+	return x.WriteDigitsContext(context.Background(), digits)
+}
+
+// WriteDigits that can be cancelled.
+func (x *DISP16KEY) WriteDigitsContext(ctx context.Context, digits [8]byte) error {
+
+	logical := digits
+	digits = x.Orientation.PhysicalDigits(digits)
 
 	// For the 16-key, we have to convert the digits into a different format than used by the 8-key (and thus our other processing methods as well)
 	digits = convertEightKeyDigits(digits)
-	fmt.Println(">:>", digits)
 
-	for i := 0; i < len(digits); i++ {
-		x.digitBuffer[0] = digits[i]
-		// Skipping over the LED bytes
-		x.WriteData(i*2, x.digitBuffer[0:1])
+	writes := make([]AddressByte, 8)
+	for i := 0; i < 8; i++ {
+		// Skipping over the unused bytes
+		writes[i] = AddressByte{i * 2, digits[i]}
 	}
-	return nil
+	return x.TransactionContext(ctx, func(bus TM1638Bus) error {
+		err := bus.WriteScattered(writes)
+		if err == nil {
+			x.digits = logical
+		}
+		return err
+	})
+}
+
+// The digits last written with WriteDigits or WriteString, in logical
+// positions. Unlike the physical display RAM, this still has the point after
+// the last digit when the Orientation can't show it.
+func (x *DISP16KEY) Digits() [8]byte {
+	x.lock()
+	defer x.release()
+	return x.digits
 }
 
 // Print the string to the display using the configured font mapping.
 // This writes from left to right and blanks any unused digits to the right.
 // chars = the text string.
 func (x *DISP16KEY) WriteString(chars string) error {
-	err := x.BuildDigits(chars, 8, x.digitBuffer[:])
+	return x.WriteStringContext(context.Background(), chars)
+}
+
+// WriteString that can be cancelled.
+func (x *DISP16KEY) WriteStringContext(ctx context.Context, chars string) error {
+	var digits [8]byte
+	err := x.BuildDigits(chars, 8, digits[:])
 	if err != nil {
 		return err
 	}
 
-	return x.WriteDigits(x.digitBuffer)
+	return x.WriteDigitsContext(ctx, digits)
+}
+
+// What the display shows, decoded with the font (like "3.14    "). This
+// comes from the digits last written (see Digits), handy for logging.
+func (x *DISP16KEY) DisplayText() string {
+	digits := x.Digits()
+	return x.DecodeDigits(digits[:])
 }
 
 // Read the 16 buttons
 // Fills in button booleans from left to right and top to bottom
 func (x *DISP16KEY) ReadButtons(buttons *[16]bool) error {
+	return x.ReadButtonsContext(context.Background(), buttons)
+}
 
-	data := []byte{0, 0, 0, 0}
-	err := x.ReadScanningData(data)
+// ReadButtons that can be cancelled.
+func (x *DISP16KEY) ReadButtonsContext(ctx context.Context, buttons *[16]bool) error {
+
+	m, err := x.ReadKeyMatrixContext(ctx)
 	if err != nil {
 		return err
 	}
-
-	buttons[0] = data[0]&0x20 > 0
-	buttons[1] = data[0]&0x02 > 0
-	buttons[2] = data[1]&0x20 > 0
-	buttons[3] = data[1]&0x02 > 0
-	buttons[4] = data[2]&0x20 > 0
-	buttons[5] = data[2]&0x02 > 0
-	buttons[6] = data[3]&0x20 > 0
-	buttons[7] = data[3]&0x02 > 0
-	buttons[8] = data[0]&0x40 > 0
-	buttons[9] = data[0]&0x04 > 0
-	buttons[10] = data[1]&0x40 > 0
-	buttons[11] = data[1]&0x04 > 0
-	buttons[12] = data[2]&0x40 > 0
-	buttons[13] = data[2]&0x04 > 0
-	buttons[14] = data[3]&0x40 > 0
-	buttons[15] = data[3]&0x04 > 0
+	m.Decode(x.Orientation.Keys(LayoutDISP16KEY, DISP16KEYKeys[:]), buttons[:])
 
 	return nil
 }
@@ -0,0 +1,401 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	Fonts can be loaded from (and saved to) files. Each glyph is a character
+	and the letters of its lit segments:
+
+	     a
+	   f   b
+	     g
+	   e   c
+	     d   dp
+
+	The text format is one glyph per line. Blank lines and lines starting
+	with '#' are ignored. "-" is the glyph with no segments. A few
+	characters are written as escapes: \s is the space, \# the hash, \\ the
+	backslash and \xHH any other byte.
+
+	  # Digits
+	  0 abcdef
+	  1 bc
+	  \s -
+	  . dp
+
+	The JSON format is an object from character to letters, with "" for no
+	segments. Wrap the font in a FontFile to marshal it:
+
+	  {"0": "abcdef", "1": "bc", " ": "", ".": "dp"}
+
+	Characters are single bytes because BuildDigits works byte by byte. In
+	JSON, byte values 0x80 to 0xFF are the characters U+0080 to U+00FF.
+
+	WideFont files are the same with space-separated segment names (see
+	wideFont.go). The file doesn't say which segment set it uses, so the
+	loader is told:
+
+	  A a b c e f g1 g2
+	  . dp
+
+	  {"A": "a b c e f g1 g2", ".": "dp"}
+*/
+
+// Check that the font can be used. Every character must be a single byte.
+func (x *SevenSegFont) Validate() error {
+	if len(x.font) == 0 {
+		return fmt.Errorf("Font has no glyphs")
+	}
+	for c := range x.font {
+		if c < 0 || c > 0xFF {
+			return fmt.Errorf("Font character %U is not a single byte", c)
+		}
+	}
+	return nil
+}
+
+// Replace the font's glyphs with a copy of another font's.
+func (x *SevenSegFont) SetFont(other *SevenSegFont) {
+	x.font = make(map[int]byte, len(other.font))
+	for c, p := range other.font {
+		x.font[c] = p
+	}
+}
+
+// Check that the font can be used. Every character must be a single byte
+// and every glyph must fit the segment set.
+func (x *WideFont) Validate() error {
+	if len(x.font) == 0 {
+		return fmt.Errorf("Font has no glyphs")
+	}
+	for c, g := range x.font {
+		if c < 0 || c > 0xFF {
+			return fmt.Errorf("Font character %U is not a single byte", c)
+		}
+		if g&^wideSegmentMask(x.Segments) != 0 {
+			return fmt.Errorf("Font character %U has segments outside its %d-segment set", c, len(x.Segments))
+		}
+	}
+	return nil
+}
+
+// Replace the font's segment set and glyphs with a copy of another font's.
+func (x *WideFont) SetFont(other *WideFont) {
+	x.Segments = other.Segments
+	x.font = make(map[int]WideGlyph, len(other.font))
+	for c, g := range other.font {
+		x.font[c] = g
+	}
+}
+
+// Read a font in the text format.
+func LoadSevenSegFont(r io.Reader) (*SevenSegFont, error) {
+	ret := &SevenSegFont{font: map[int]byte{}}
+	err := readFontLines(r, func(c int, segments []string) error {
+		if len(segments) != 1 {
+			return fmt.Errorf("expected a character and segments")
+		}
+		if segments[0] == "-" {
+			ret.font[c] = 0
+			return nil
+		}
+		pattern, err := Segments(segments[0])
+		ret.font[c] = pattern
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, ret.Validate()
+}
+
+// Read a wide font in the text format. set is the font's segment set,
+// Segments14 or Segments16.
+func LoadWideFont(r io.Reader, set []string) (*WideFont, error) {
+	ret := &WideFont{Segments: set, font: map[int]WideGlyph{}}
+	err := readFontLines(r, func(c int, segments []string) error {
+		if len(segments) == 1 && segments[0] == "-" {
+			ret.font[c] = 0
+			return nil
+		}
+		glyph, err := WideSegments(set, strings.Join(segments, " "))
+		ret.font[c] = glyph
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, ret.Validate()
+}
+
+// Read the glyph lines of a text font. add gets each glyph's character and
+// segment fields.
+func readFontLines(r io.Reader, add func(c int, segments []string) error) error {
+	seen := map[int]bool{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return fmt.Errorf("Line %d: expected a character and segments: %s", line, text)
+		}
+		c, err := parseFontChar(fields[0])
+		if err != nil {
+			return fmt.Errorf("Line %d: %v", line, err)
+		}
+		if seen[c] {
+			return fmt.Errorf("Line %d: '%s' is defined twice", line, fields[0])
+		}
+		seen[c] = true
+		err = add(c, fields[1:])
+		if err != nil {
+			return fmt.Errorf("Line %d: %v: %s", line, err, text)
+		}
+	}
+	return scanner.Err()
+}
+
+// Write the font in the text format, in character order.
+func (x *SevenSegFont) Save(w io.Writer) error {
+	chars := []int{}
+	for c := range x.font {
+		chars = append(chars, c)
+	}
+	return writeFontLines(w, chars, func(c int) string {
+		return strings.Join(SegmentNames(x.font[c]), "")
+	})
+}
+
+// Write the wide font in the text format, in character order.
+func (x *WideFont) Save(w io.Writer) error {
+	chars := []int{}
+	for c := range x.font {
+		chars = append(chars, c)
+	}
+	return writeFontLines(w, chars, func(c int) string {
+		return strings.Join(WideSegmentNames(x.Segments, x.font[c]), " ")
+	})
+}
+
+// Write one line per character with the segments from names.
+func writeFontLines(w io.Writer, chars []int, names func(c int) string) error {
+	sort.Ints(chars)
+	b := bufio.NewWriter(w)
+	for _, c := range chars {
+		segments := names(c)
+		if segments == "" {
+			segments = "-"
+		}
+		fmt.Fprintf(b, "%s %s\n", formatFontChar(c), segments)
+	}
+	return b.Flush()
+}
+
+// A font in the JSON format. The JSON methods are on this wrapper rather
+// than on SevenSegFont so the boards (which embed the font) marshal as
+// themselves.
+//
+//	data, err := json.Marshal(pkg.FontFile{Font: &p.SevenSegFont})
+type FontFile struct {
+	Font *SevenSegFont
+}
+
+// Write the font as a JSON object.
+func (x FontFile) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(x.Font.font))
+	for c, p := range x.Font.font {
+		m[string(rune(c))] = strings.Join(SegmentNames(p), "")
+	}
+	return json.Marshal(m)
+}
+
+// Read the font from a JSON object, replacing all its glyphs. A nil Font
+// gets a new one.
+func (x *FontFile) UnmarshalJSON(data []byte) error {
+	m := map[string]string{}
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+	font := make(map[int]byte, len(m))
+	for k, v := range m {
+		c, err := jsonFontChar(k)
+		if err != nil {
+			return err
+		}
+		pattern, err := Segments(v)
+		if err != nil {
+			return fmt.Errorf("'%s': %v", k, err)
+		}
+		font[c] = pattern
+	}
+	ret := SevenSegFont{font: font}
+	err = ret.Validate()
+	if err != nil {
+		return err
+	}
+	if x.Font == nil {
+		x.Font = &SevenSegFont{}
+	}
+	x.Font.font = font
+	return nil
+}
+
+// A wide font in the JSON format, like FontFile.
+//
+//	data, err := json.Marshal(pkg.WideFontFile{Font: &p.WideFont})
+type WideFontFile struct {
+	Font *WideFont
+}
+
+// Write the font as a JSON object.
+func (x WideFontFile) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(x.Font.font))
+	for c, g := range x.Font.font {
+		m[string(rune(c))] = strings.Join(WideSegmentNames(x.Font.Segments, g), " ")
+	}
+	return json.Marshal(m)
+}
+
+// Read the font from a JSON object, replacing all its glyphs. The glyphs
+// use the Font's segment set. A nil Font gets a new 14-segment one.
+func (x *WideFontFile) UnmarshalJSON(data []byte) error {
+	m := map[string]string{}
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+	set := Segments14
+	if x.Font != nil && x.Font.Segments != nil {
+		set = x.Font.Segments
+	}
+	font := make(map[int]WideGlyph, len(m))
+	for k, v := range m {
+		c, err := jsonFontChar(k)
+		if err != nil {
+			return err
+		}
+		glyph, err := WideSegments(set, v)
+		if err != nil {
+			return fmt.Errorf("'%s': %v", k, err)
+		}
+		font[c] = glyph
+	}
+	ret := WideFont{Segments: set, font: font}
+	err = ret.Validate()
+	if err != nil {
+		return err
+	}
+	if x.Font == nil {
+		x.Font = &WideFont{}
+	}
+	x.Font.Segments = set
+	x.Font.font = font
+	return nil
+}
+
+// The character of a JSON font key: one rune from U+0000 to U+00FF.
+func jsonFontChar(k string) (int, error) {
+	c, size := utf8.DecodeRuneInString(k)
+	if size != len(k) || c == utf8.RuneError || c > 0xFF {
+		return 0, fmt.Errorf("Font character \"%s\" is not a single byte", k)
+	}
+	return int(c), nil
+}
+
+// Read a font file. Files ending in ".json" are JSON, anything else is the
+// text format.
+func LoadSevenSegFontFile(path string) (*SevenSegFont, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		file := FontFile{}
+		err = json.NewDecoder(f).Decode(&file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return file.Font, nil
+	}
+	ret, err := LoadSevenSegFont(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ret, nil
+}
+
+// Read a wide font file with the segment set, like LoadSevenSegFontFile.
+func LoadWideFontFile(path string, set []string) (*WideFont, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		file := WideFontFile{Font: &WideFont{Segments: set}}
+		err = json.NewDecoder(f).Decode(&file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return file.Font, nil
+	}
+	ret, err := LoadWideFont(f, set)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ret, nil
+}
+
+// The character in the first field of a text font line.
+func parseFontChar(field string) (int, error) {
+	switch field {
+	case `\s`:
+		return ' ', nil
+	case `\#`:
+		return '#', nil
+	case `\\`:
+		return '\\', nil
+	}
+	if len(field) == 4 && strings.HasPrefix(field, `\x`) {
+		v, err := strconv.ParseUint(field[2:], 16, 8)
+		if err == nil {
+			return int(v), nil
+		}
+	}
+	if len(field) != 1 {
+		return 0, fmt.Errorf("'%s' is not a single byte character", field)
+	}
+	return int(field[0]), nil
+}
+
+func formatFontChar(c int) string {
+	switch {
+	case c == ' ':
+		return `\s`
+	case c == '#':
+		return `\#`
+	case c == '\\':
+		return `\\`
+	case c > ' ' && c < 0x7F:
+		return string(rune(c))
+	}
+	return fmt.Sprintf(`\x%02X`, c)
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A font with every byte value, each with a different glyph where possible.
+func allBytesFont() *SevenSegFont {
+	font := &SevenSegFont{font: map[int]byte{}}
+	for c := 0; c < 256; c++ {
+		font.font[c] = byte(c * 7)
+	}
+	return font
+}
+
+func expectSameFont(t *testing.T, got *SevenSegFont, want *SevenSegFont) {
+	t.Helper()
+	if len(got.font) != len(want.font) {
+		t.Fatalf("font has %d glyphs, expected %d", len(got.font), len(want.font))
+	}
+	for c, p := range want.font {
+		if got.font[c] != p {
+			t.Errorf("glyph 0x%02X is %08b, expected %08b", c, got.font[c], p)
+		}
+	}
+}
+
+func TestFontJSONRoundTrip(t *testing.T) {
+	want := allBytesFont()
+	data, err := json.Marshal(FontFile{Font: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) || !bytes.Contains(data, []byte(`"\u0000":""`)) {
+		t.Errorf("bad JSON: %s", data)
+	}
+	var got FontFile
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	expectSameFont(t, got.Font, want)
+}
+
+func TestFontJSONErrors(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"ab": "bc"}`,
+		`{"Ā": "bc"}`,
+		`{"1": "bcx"}`,
+	} {
+		var f FontFile
+		if err := json.Unmarshal([]byte(data), &f); err == nil {
+			t.Errorf("no error for %s", data)
+		}
+	}
+}
+
+func TestFontTextRoundTrip(t *testing.T) {
+	want := allBytesFont()
+	var text bytes.Buffer
+	if err := want.Save(&text); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadSevenSegFont(&text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSameFont(t, got, want)
+}
+
+func TestLoadSevenSegFontFile(t *testing.T) {
+	dir := t.TempDir()
+	want := NewSevenSegFont()
+
+	data, err := json.Marshal(FontFile{Font: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "font.json")
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadSevenSegFontFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSameFont(t, got, want)
+
+	var text bytes.Buffer
+	if err := want.Save(&text); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "font.txt")
+	if err := os.WriteFile(textPath, text.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = LoadSevenSegFontFile(textPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSameFont(t, got, want)
+}
+
+// Boards embed the font, but marshal as themselves.
+func TestBoardJSONIsNotTheFont(t *testing.T) {
+	p := NewLED8KEY(NewTM1638Sim().Pins())
+	p.Orientation = OrientationMirrorY
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Orientation":3`) {
+		t.Errorf("board marshalled as %s", data)
+	}
+}
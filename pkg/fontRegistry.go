@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Named fonts that any board can switch to with UseFont. "default" is the
+// built-in font. The registry keeps its own copies, so changing a font
+// after registering it (or after UseFont) doesn't affect the others.
+//
+// Wide fonts have their own names, starting with "default14" and
+// "default16". Those two are only built the first time a wide font is
+// registered or looked up, so a program with no wide fonts doesn't pay for
+// them.
+var fontRegistry = struct {
+	lock  sync.Mutex
+	fonts map[string]*SevenSegFont
+	wide  map[string]*WideFont // nil until first used (see wideFonts)
+}{
+	fonts: map[string]*SevenSegFont{"default": NewSevenSegFont()},
+}
+
+// The registered wide fonts, with the built-in ones made on first use. Call
+// with the lock held.
+func wideFonts() map[string]*WideFont {
+	if fontRegistry.wide == nil {
+		fontRegistry.wide = map[string]*WideFont{"default14": NewWideFont14(), "default16": NewWideFont16()}
+	}
+	return fontRegistry.wide
+}
+
+// Add (or replace) a named font.
+func RegisterFont(name string, font *SevenSegFont) error {
+	err := font.Validate()
+	if err != nil {
+		return fmt.Errorf("Font \"%s\": %v", name, err)
+	}
+	own := &SevenSegFont{}
+	own.SetFont(font)
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	fontRegistry.fonts[name] = own
+	return nil
+}
+
+// Load a font file (see LoadSevenSegFontFile) and register it.
+func RegisterFontFile(name string, path string) error {
+	font, err := LoadSevenSegFontFile(path)
+	if err != nil {
+		return err
+	}
+	return RegisterFont(name, font)
+}
+
+// A copy of a named font.
+func LookupFont(name string) (*SevenSegFont, error) {
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	font, exist := fontRegistry.fonts[name]
+	if !exist {
+		return nil, fmt.Errorf("No font named \"%s\"", name)
+	}
+	ret := &SevenSegFont{}
+	ret.SetFont(font)
+	return ret, nil
+}
+
+// The names of all the registered fonts, sorted.
+func FontNames() []string {
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	ret := []string{}
+	for name := range fontRegistry.fonts {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Switch to a named font, like p.UseFont("branded").
+func (x *SevenSegFont) UseFont(name string) error {
+	font, err := LookupFont(name)
+	if err != nil {
+		return err
+	}
+	x.font = font.font
+	return nil
+}
+
+// Add (or replace) a named wide font.
+func RegisterWideFont(name string, font *WideFont) error {
+	err := font.Validate()
+	if err != nil {
+		return fmt.Errorf("Font \"%s\": %v", name, err)
+	}
+	own := &WideFont{}
+	own.SetFont(font)
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	wideFonts()[name] = own
+	return nil
+}
+
+// Load a wide font file (see LoadWideFontFile) and register it.
+func RegisterWideFontFile(name string, path string, set []string) error {
+	font, err := LoadWideFontFile(path, set)
+	if err != nil {
+		return err
+	}
+	return RegisterWideFont(name, font)
+}
+
+// A copy of a named wide font.
+func LookupWideFont(name string) (*WideFont, error) {
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	font, exist := wideFonts()[name]
+	if !exist {
+		return nil, fmt.Errorf("No wide font named \"%s\"", name)
+	}
+	ret := &WideFont{}
+	ret.SetFont(font)
+	return ret, nil
+}
+
+// The names of all the registered wide fonts, sorted.
+func WideFontNames() []string {
+	fontRegistry.lock.Lock()
+	defer fontRegistry.lock.Unlock()
+	ret := []string{}
+	for name := range wideFonts() {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Switch to a named wide font, segment set and all.
+func (x *WideFont) UseFont(name string) error {
+	font, err := LookupWideFont(name)
+	if err != nil {
+		return err
+	}
+	x.Segments = font.Segments
+	x.font = font.font
+	return nil
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	A glyph is one digit's segment pattern, one bit per segment (xgfedcba):
+
+	  bit  7  6  5  4  3  2  1  0
+	       dp g  f  e  d  c  b  a
+
+	Build them from segment names instead of binary literals:
+
+	  MustSegments("abcdef")        // 0
+	  MustSegments("b", "c", "dp")  // 1.
+
+	or from ASCII art in the same shape the terminal front end draws:
+
+	  MustGlyphArt(`
+	 _
+	|_|
+	|_|.`)                          // 8.
+
+	SegmentLayout() prints this layout from the tables below.
+*/
+
+// The segment bits
+const (
+	SegA  byte = 1 << iota // Top
+	SegB                   // Top right
+	SegC                   // Bottom right
+	SegD                   // Bottom
+	SegE                   // Bottom left
+	SegF                   // Top left
+	SegG                   // Middle
+	SegDP                  // Decimal point
+)
+
+// The segment names in bit order (xgfedcba)
+var segmentNames = [8]string{"a", "b", "c", "d", "e", "f", "g", "dp"}
+
+// Where each segment is drawn in the art: row, column and character
+var segmentArt = [8]struct {
+	Row, Col int
+	Char     byte
+}{
+	{0, 1, '_'}, // a
+	{1, 2, '|'}, // b
+	{2, 2, '|'}, // c
+	{2, 1, '_'}, // d
+	{2, 0, '|'}, // e
+	{1, 0, '|'}, // f
+	{1, 1, '_'}, // g
+	{2, 3, '.'}, // dp
+}
+
+// Build a glyph from segment names. Each name is a segment ("a" to "g" or
+// "dp") or a run of them ("abcdef", "bcdp").
+func Segments(names ...string) (byte, error) {
+	var ret byte
+	for _, name := range names {
+		for i := 0; i < len(name); i++ {
+			var bit byte
+			switch {
+			case strings.HasPrefix(name[i:], "dp"):
+				bit = SegDP
+				i++
+			case name[i] >= 'a' && name[i] <= 'g':
+				bit = 1 << (name[i] - 'a')
+			default:
+				return 0, fmt.Errorf("Unknown segment '%c' in \"%s\"", name[i], name)
+			}
+			if ret&bit != 0 {
+				return 0, fmt.Errorf("Segment repeated in \"%s\"", name)
+			}
+			ret |= bit
+		}
+	}
+	return ret, nil
+}
+
+// Segments for names known to be good, like the ones in a font table.
+// Panics on a bad name.
+func MustSegments(names ...string) byte {
+	ret, err := Segments(names...)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// The names of the glyph's lit segments in bit order.
+func SegmentNames(glyph byte) []string {
+	ret := []string{}
+	for i, name := range segmentNames {
+		if glyph&(1<<uint(i)) != 0 {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// Build a glyph from three lines of ASCII art (see the top of this file).
+// Any character other than a space lights the segment drawn there. Blank
+// lines at the end and leading tabs are ignored. If there are four lines,
+// the first must be empty: that is the newline after the opening quote of a
+// tab-indented raw string. This reads back anything GlyphArt draws.
+func ParseGlyphArt(art string) (byte, error) {
+	lines := strings.Split(strings.Replace(art, "\r", "", -1), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 4 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 3 {
+		return 0, fmt.Errorf("Glyph art has %d lines, expected 3", len(lines))
+	}
+
+	var grid [3]string
+	for i, line := range lines {
+		grid[i] = strings.TrimLeft(line, "\t")
+	}
+
+	var ret byte
+	for row := range grid {
+		for col := 0; col < len(grid[row]); col++ {
+			if grid[row][col] == ' ' {
+				continue
+			}
+			bit := -1
+			for i, s := range segmentArt {
+				if s.Row == row && s.Col == col {
+					bit = i
+				}
+			}
+			if bit < 0 {
+				return 0, fmt.Errorf("No segment at line %d column %d of the glyph art", row+1, col+1)
+			}
+			ret |= 1 << uint(bit)
+		}
+	}
+	return ret, nil
+}
+
+// ParseGlyphArt for art known to be good. Panics on bad art.
+func MustGlyphArt(art string) byte {
+	ret, err := ParseGlyphArt(art)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Draw the glyph as three lines of ASCII art, each ending in "\n".
+func GlyphArt(glyph byte) string {
+	return glyphArt(glyph, func(i int) byte { return segmentArt[i].Char })
+}
+
+// Describe the glyph layout: each segment's bit and mask, and where it is
+// drawn.
+func SegmentLayout() string {
+	var b strings.Builder
+	b.WriteString("bit  mask        segment\n")
+	for i := len(segmentNames) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%d    0b%08b  %s\n", i, 1<<uint(i), segmentNames[i])
+	}
+	// The art with each segment drawn as its letter (dp is x)
+	b.WriteString("\n")
+	art := glyphArt(0xFF, func(i int) byte { return "abcdefgx"[i] })
+	for _, line := range strings.SplitAfter(art, "\n") {
+		if line != "" {
+			b.WriteString("  " + line)
+		}
+	}
+	return b.String()
+}
+
+// Draw the lit segments with the given characters.
+func glyphArt(glyph byte, char func(segment int) byte) string {
+	grid := [3][4]byte{}
+	for r := range grid {
+		for c := range grid[r] {
+			grid[r][c] = ' '
+		}
+	}
+	for i, s := range segmentArt {
+		if glyph&(1<<uint(i)) != 0 {
+			grid[s.Row][s.Col] = char(i)
+		}
+	}
+	ret := ""
+	for _, row := range grid {
+		ret += strings.TrimRight(string(row[:]), " ") + "\n"
+	}
+	return ret
+}
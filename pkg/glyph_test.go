@@ -0,0 +1,60 @@
+package pkg
+
+import "testing"
+
+func TestGlyphArtRoundTrip(t *testing.T) {
+	for g := 0; g < 256; g++ {
+		art := GlyphArt(byte(g))
+		got, err := ParseGlyphArt(art)
+		if err != nil {
+			t.Errorf("%08b: %v in\n%s", g, err, art)
+			continue
+		}
+		if got != byte(g) {
+			t.Errorf("%08b read back as %08b from\n%s", g, got, art)
+		}
+	}
+}
+
+func TestParseGlyphArt(t *testing.T) {
+	tests := []struct {
+		art  string
+		want byte
+	}{
+		{"\n _\n|_|\n|_|.", 0xFF},
+		{" _\n|_|\n|_|.", 0xFF},
+		{"\n| |\n|_|", MustSegments("bcdef")},
+		{"\n _\n", SegG},
+		{"\n\t _\n\t  |\n\t  |\n\t", MustSegments("abc")},
+		{"", 0},
+	}
+	for _, test := range tests {
+		got, err := ParseGlyphArt(test.art)
+		if err != nil || got != test.want {
+			t.Errorf("%q read as %08b, %v. Expected %08b", test.art, got, err, test.want)
+		}
+	}
+
+	for _, art := range []string{"x", " _\n|_|\n|_|\n|", "\n\n\n\n_"} {
+		if _, err := ParseGlyphArt(art); err == nil {
+			t.Errorf("no error for %q", art)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	if g := MustSegments("b", "c", "dp"); g != SegB|SegC|SegDP {
+		t.Errorf("b c dp is %08b", g)
+	}
+	for _, names := range []string{"h", "aa", "dpdp"} {
+		if _, err := Segments(names); err == nil {
+			t.Errorf("no error for %q", names)
+		}
+	}
+	for g := 0; g < 256; g++ {
+		got, err := Segments(SegmentNames(byte(g))...)
+		if err != nil || got != byte(g) {
+			t.Errorf("%08b named %v read back as %08b, %v", g, SegmentNames(byte(g)), got, err)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package pkg
 
+import "context"
+
 /*
     This is the memory layout for the buttons and LEDs on the
 	LED8Key board.
@@ -35,89 +37,141 @@ package pkg
 	15   LED 8 (right most LED)
 
 	All four of the read-scanning-keys bytes are used to capture the 8 buttons
-	on the board:
-
-	Buttons from left to right, A to H map to bits in the four return bytes:
-	0    A000E000
-	1    B000F000
-	2    C000G000
-	3    D000H000
+	on the board. The buttons are all on K3 (see tm1638Keys.go for the
+	datasheet's K/SEG layout).
+
+	Buttons from left to right, A to H map to bits (B7..B0) in the four
+	return bytes:
+	0    000E000A    A=K3/SEG1  E=K3/SEG2
+	1    000F000B    B=K3/SEG3  F=K3/SEG4
+	2    000G000C    C=K3/SEG5  G=K3/SEG6
+	3    000H000D    D=K3/SEG7  H=K3/SEG8
 */
 
+// Where the buttons (left to right) are in the TM1638's key matrix.
+var LED8KEYKeys = [8]KeyPosition{
+	{3, 1}, {3, 3}, {3, 5}, {3, 7},
+	{3, 2}, {3, 4}, {3, 6}, {3, 8},
+}
+
 type LED8KEY struct {
 	TM1638
 	SevenSegFont
-	digitBuffer [8]byte
+	Orientation Orientation // How the board is mounted
+	digits      [8]byte     // The logical digits last written (see Digits)
 }
 
 // Create a new LED8Key driver with the given pin numbers. These numbers
 // are the RPi's BCM pin numbers -- not the board pin numbers on the IO header.
 // See the "What do these numbers mean?" section here: https://pinout.xyz/
-func NewLED8KEY(pinSTROBE GPIOPin, pinCLK GPIOPin, pinDIO GPIOPin) *LED8KEY {
+func NewLED8KEY(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) *LED8KEY {
 	ret := &LED8KEY{}
-	ret.TM1638 = *NewTM1638(pinSTROBE, pinCLK, pinDIO)
+	ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
 	ret.ResetFont()
 	return ret
 }
 
+// NewLED8KEY that reports the first error from setting up the lines.
+func NewLED8KEYE(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) (*LED8KEY, error) {
+	ret := &LED8KEY{}
+	err := ret.TM1638.setup(pinSTROBE, pinCLK, pinDIO)
+	ret.ResetFont()
+	return ret, err
+}
+
 // Set the status of the LEDs.
 // leds = slice of booleans left to right, true means on
 func (x *LED8KEY) SetLEDs(leds [8]bool) error {
+	return x.SetLEDsContext(context.Background(), leds)
+}
 
+// SetLEDs that can be cancelled.
+func (x *LED8KEY) SetLEDsContext(ctx context.Context, leds [8]bool) error {
+	leds = x.Orientation.LEDs(leds)
+	writes := make([]AddressByte, 8)
 	for i := 0; i < 8; i++ {
-		data := byte(0)
+		writes[i].Address = i*2 + 1
 		if leds[i] {
-			data = byte(1)
+			writes[i].Data = 1
 		}
-		x.WriteData(i*2+1, []byte{data})
 	}
-
-	return nil
+	return x.WriteScatteredContext(ctx, writes)
 }
 
 // Write 8 display digits.
 // digits = array of raw bit patterns for each display
 func (x *LED8KEY) WriteDigits(digits [8]byte) error {
-	// We should consider keeping a back-buffer of all 16 bytes and always
-	// write them together with a "Refresh" method.
-	for i := 0; i < len(digits); i++ {
-		x.digitBuffer[0] = digits[i]
+	return x.WriteDigitsContext(context.Background(), digits)
+}
+
+// WriteDigits that can be cancelled.
+func (x *LED8KEY) WriteDigitsContext(ctx context.Context, digits [8]byte) error {
+	// Only the digit bytes are written. The TM1638 shadows all 16 bytes,
+	// so Frame and Restore still see the LEDs.
+	logical := digits
+	digits = x.Orientation.PhysicalDigits(digits)
+	writes := make([]AddressByte, 8)
+	for i := 0; i < 8; i++ {
 		// Skipping over the LED bytes
-		x.WriteData(i*2, x.digitBuffer[0:1])
+		writes[i] = AddressByte{i * 2, digits[i]}
 	}
-	return nil
+	return x.TransactionContext(ctx, func(bus TM1638Bus) error {
+		err := bus.WriteScattered(writes)
+		if err == nil {
+			x.digits = logical
+		}
+		return err
+	})
+}
+
+// The digits last written with WriteDigits or WriteString, in logical
+// positions. Unlike the physical display RAM, this still has the point after
+// the last digit when the Orientation can't show it.
+func (x *LED8KEY) Digits() [8]byte {
+	x.lock()
+	defer x.release()
+	return x.digits
 }
 
 // Print the string to the display using the configured font mapping.
 // This writes from left to right and blanks any unused digits to the right.
 // chars = the text string.
 func (x *LED8KEY) WriteString(chars string) error {
-	err := x.BuildDigits(chars, 8, x.digitBuffer[:])
+	return x.WriteStringContext(context.Background(), chars)
+}
+
+// WriteString that can be cancelled.
+func (x *LED8KEY) WriteStringContext(ctx context.Context, chars string) error {
+	var digits [8]byte
+	err := x.BuildDigits(chars, 8, digits[:])
 	if err != nil {
 		return err
 	}
 
-	return x.WriteDigits(x.digitBuffer)
+	return x.WriteDigitsContext(ctx, digits)
+}
+
+// What the display shows, decoded with the font (like "3.14    "). This
+// comes from the digits last written (see Digits), handy for logging.
+func (x *LED8KEY) DisplayText() string {
+	digits := x.Digits()
+	return x.DecodeDigits(digits[:])
 }
 
 // Read the 8 buttons
 // Returns an array of booleans from left to right, true means pressed
 func (x *LED8KEY) ReadButtons(buttons *[8]bool) error {
+	return x.ReadButtonsContext(context.Background(), buttons)
+}
+
+// ReadButtons that can be cancelled.
+func (x *LED8KEY) ReadButtonsContext(ctx context.Context, buttons *[8]bool) error {
 
-	data := []byte{0, 0, 0, 0}
-	err := x.ReadScanningData(data)
+	m, err := x.ReadKeyMatrixContext(ctx)
 	if err != nil {
 		return err
 	}
-
-	buttons[0] = data[0]&0x80 > 0
-	buttons[1] = data[1]&0x80 > 0
-	buttons[2] = data[2]&0x80 > 0
-	buttons[3] = data[3]&0x80 > 0
-	buttons[4] = data[0]&0x08 > 0
-	buttons[5] = data[1]&0x08 > 0
-	buttons[6] = data[2]&0x08 > 0
-	buttons[7] = data[3]&0x08 > 0
+	m.Decode(x.Orientation.Keys(LayoutLED8KEY, LED8KEYKeys[:]), buttons[:])
 
 	return nil
 }
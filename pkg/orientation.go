@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	How the board is mounted. Apps keep using logical positions (digit 0
+	and button 0 are where the viewer expects the first ones) and the board
+	moves them to the physical ones.
+
+	Turned upside down (OrientationRotate180), the digits come in reverse
+	order and each digit's segments turn around:
+
+	   _                  _
+	  |_   (a, f, g)  ->   |  (d, c, g)
+	                      -
+
+	The decimal point ends up at the top left of the digit, so the point
+	after logical digit i is drawn by the digit to its right. The point
+	after the last logical digit has no digit to draw it, so it is dropped.
+	The physical point at the top left of the first logical digit is never
+	lit. Apart from that point, LogicalDigits undoes PhysicalDigits. The
+	boards keep the logical digits they last wrote (see LED8KEY.Digits),
+	so DisplayText still shows that point.
+
+	Mirroring left to right reverses the digits and swaps the sides (like a
+	display seen in a mirror). The points move like they do for the
+	rotation. Mirroring top to bottom keeps the digit order and swaps the
+	top and bottom.
+
+	The LED8KEY's LEDs and buttons are reversed along with the digits. The
+	DISP16KEY's 4x4 key grid is rotated or mirrored the same way as the
+	display.
+
+	An AlnumBoard's 14- and 16-segment digits turn the same way, swapping
+	the diagonals and the halves of the split bars too.
+
+	Only the chip drivers (LED8KEY, DISP16KEY and AlnumBoard) have an
+	Orientation. A
+	VirtualBoard and its front ends (Terminal, WebBoard, BoardRenderer)
+	always show the physical layout: the chip's display RAM drawn upright.
+*/
+
+type Orientation int
+
+const (
+	OrientationNormal    Orientation = iota
+	OrientationRotate180             // Upside down
+	OrientationMirrorX               // Mirrored left to right
+	OrientationMirrorY               // Mirrored top to bottom
+)
+
+// Where each segment (a to g) goes
+var orientationSegments = [4][7]uint{
+	{0, 1, 2, 3, 4, 5, 6}, // Normal
+	{3, 4, 5, 0, 1, 2, 6}, // a<>d b<>e c<>f
+	{0, 5, 4, 3, 2, 1, 6}, // b<>f c<>e
+	{3, 2, 1, 0, 5, 4, 6}, // a<>d b<>c e<>f
+}
+
+// The wide segments that swap places (see wideFont.go). Segments that
+// aren't listed stay put.
+var orientationWideSegments = [4][]string{
+	{},
+	{"a d", "b e", "c f", "g1 g2", "h m", "i l", "j k", "a1 d2", "a2 d1"},
+	{"b f", "c e", "g1 g2", "h j", "k m", "a1 a2", "d1 d2"},
+	{"a d", "b c", "e f", "h k", "i l", "j m", "a1 d1", "a2 d2"},
+}
+
+func (o Orientation) String() string {
+	switch o {
+	case OrientationNormal:
+		return "Normal"
+	case OrientationRotate180:
+		return "Rotate180"
+	case OrientationMirrorX:
+		return "MirrorX"
+	case OrientationMirrorY:
+		return "MirrorY"
+	}
+	return fmt.Sprintf("Orientation(%d)", int(o))
+}
+
+// True if the orientation reverses the digit order (and moves the points).
+func (o Orientation) reversed() bool {
+	return o == OrientationRotate180 || o == OrientationMirrorX
+}
+
+// Turn one glyph's segments a to g. The decimal point is left alone.
+func (o Orientation) Glyph(glyph byte) byte {
+	if o < OrientationNormal || o > OrientationMirrorY {
+		return glyph
+	}
+	ret := glyph & SegDP
+	for i, to := range orientationSegments[o] {
+		if glyph&(1<<uint(i)) != 0 {
+			ret |= 1 << to
+		}
+	}
+	return ret
+}
+
+// Undo Glyph.
+func (o Orientation) unGlyph(glyph byte) byte {
+	if o < OrientationNormal || o > OrientationMirrorY {
+		return glyph
+	}
+	ret := glyph & SegDP
+	for i, to := range orientationSegments[o] {
+		if glyph&(1<<to) != 0 {
+			ret |= 1 << uint(i)
+		}
+	}
+	return ret
+}
+
+// The physical digits for the logical digits.
+func (o Orientation) PhysicalDigits(digits [8]byte) [8]byte {
+	var ret [8]byte
+	moveDigits(o, digits[:], ret[:], SegDP, o.Glyph)
+	return ret
+}
+
+// The logical digits for the physical digits. This undoes PhysicalDigits,
+// except for the point after the last digit in a reversed orientation.
+func (o Orientation) LogicalDigits(digits [8]byte) [8]byte {
+	var ret [8]byte
+	moveDigits(o, digits[:], ret[:], SegDP, o.unGlyph)
+	return ret
+}
+
+// Turn one 14- or 16-segment glyph (set is its segment set). This is its
+// own undo. The decimal point is left alone.
+func (o Orientation) WideGlyph(set []string, glyph WideGlyph) WideGlyph {
+	if o < OrientationNormal || o > OrientationMirrorY {
+		return glyph
+	}
+	swap := map[string]string{}
+	for _, pair := range orientationWideSegments[o] {
+		names := strings.Fields(pair)
+		swap[names[0]], swap[names[1]] = names[1], names[0]
+	}
+	ret := glyph & WideSegDP
+	for _, name := range WideSegmentNames(set, glyph&^WideSegDP) {
+		if to, exist := swap[name]; exist {
+			name = to
+		}
+		ret |= MustWideSegments(set, name)
+	}
+	return ret
+}
+
+// The physical glyphs for the logical glyphs (and the other way around).
+// Like PhysicalDigits, a reversed orientation drops the last point.
+func (o Orientation) WideGlyphs(set []string, glyphs [4]WideGlyph) [4]WideGlyph {
+	var ret [4]WideGlyph
+	moveDigits(o, glyphs[:], ret[:], WideSegDP, func(g WideGlyph) WideGlyph {
+		return o.WideGlyph(set, g)
+	})
+	return ret
+}
+
+// Turn each glyph into out and, if the orientation is reversed, reverse the
+// digit order and move the points. The point of digit i is drawn by digit
+// n-2-i, so the last digit's point is dropped. The reversing is its own
+// undo, so only the glyph turn differs between the two directions.
+func moveDigits[G fontGlyph](o Orientation, digits []G, out []G, dp G, turn func(G) G) {
+	n := len(digits)
+	for i, d := range digits {
+		if !o.reversed() {
+			out[i] = turn(d)
+			continue
+		}
+		out[n-1-i] |= turn(d) &^ dp
+		if d&dp != 0 && i < n-1 {
+			out[n-2-i] |= dp
+		}
+	}
+}
+
+// The physical LEDs for the logical LEDs (and the other way around).
+func (o Orientation) LEDs(leds [8]bool) [8]bool {
+	if !o.reversed() {
+		return leds
+	}
+	var ret [8]bool
+	for i := range leds {
+		ret[7-i] = leds[i]
+	}
+	return ret
+}
+
+// The physical button for a logical button (and the other way around).
+func (o Orientation) Button(layout BoardLayout, button int) int {
+	if layout != LayoutDISP16KEY {
+		if o.reversed() {
+			return 7 - button
+		}
+		return button
+	}
+	row, col := button/4, button%4
+	switch o {
+	case OrientationRotate180:
+		row, col = 3-row, 3-col
+	case OrientationMirrorX:
+		col = 3 - col
+	case OrientationMirrorY:
+		row = 3 - row
+	}
+	return row*4 + col
+}
+
+// The board's key positions in logical button order.
+func (o Orientation) Keys(layout BoardLayout, keys []KeyPosition) []KeyPosition {
+	ret := make([]KeyPosition, len(keys))
+	for i := range keys {
+		ret[i] = keys[o.Button(layout, i)]
+	}
+	return ret
+}
@@ -0,0 +1,206 @@
+package pkg
+
+import "testing"
+
+func TestOrientationGlyph(t *testing.T) {
+	tests := []struct {
+		o    Orientation
+		in   string
+		want string
+	}{
+		{OrientationNormal, "afg", "afg"},
+		{OrientationRotate180, "afg", "cdg"},
+		{OrientationRotate180, "bcdp", "efdp"},
+		{OrientationMirrorX, "afg", "abg"},
+		{OrientationMirrorX, "bc", "ef"},
+		{OrientationMirrorY, "afg", "deg"},
+		{OrientationMirrorY, "bcdp", "bcdp"},
+	}
+	for _, test := range tests {
+		in, want := MustSegments(test.in), MustSegments(test.want)
+		if got := test.o.Glyph(in); got != want {
+			t.Errorf("%v turns %s into %v, expected %s", test.o, test.in, SegmentNames(got), test.want)
+		}
+		if got := test.o.unGlyph(want); got != in {
+			t.Errorf("%v turns %s back into %v, expected %s", test.o, test.want, SegmentNames(got), test.in)
+		}
+	}
+}
+
+func TestOrientationDigits(t *testing.T) {
+	one, two, three := MustSegments("bc"), MustSegments("abdeg"), MustSegments("abcdg")
+	dp := SegDP
+	tests := []struct {
+		o        Orientation
+		logical  [8]byte
+		physical [8]byte
+	}{
+		{
+			OrientationNormal,
+			[8]byte{one | dp, two, three},
+			[8]byte{one | dp, two, three},
+		},
+		{
+			// "1.23" upside down: the point after the 1 is drawn by the
+			// physical digit left of it
+			OrientationRotate180,
+			[8]byte{one | dp, two, three},
+			[8]byte{5: OrientationRotate180.Glyph(three), 6: OrientationRotate180.Glyph(two) | dp, 7: OrientationRotate180.Glyph(one)},
+		},
+		{
+			OrientationMirrorX,
+			[8]byte{one, two | dp, 7: three},
+			[8]byte{0: OrientationMirrorX.Glyph(three), 5: dp, 6: OrientationMirrorX.Glyph(two), 7: OrientationMirrorX.Glyph(one)},
+		},
+		{
+			OrientationMirrorY,
+			[8]byte{one | dp, two, 7: three | dp},
+			[8]byte{OrientationMirrorY.Glyph(one) | dp, OrientationMirrorY.Glyph(two), 7: OrientationMirrorY.Glyph(three) | dp},
+		},
+	}
+	for _, test := range tests {
+		if got := test.o.PhysicalDigits(test.logical); got != test.physical {
+			t.Errorf("%v physical digits % X, expected % X", test.o, got, test.physical)
+		}
+		if got := test.o.LogicalDigits(test.physical); got != test.logical {
+			t.Errorf("%v logical digits % X, expected % X", test.o, got, test.logical)
+		}
+	}
+}
+
+// The point after the last digit can't be shown upside down or mirrored.
+// It isn't drawn as a stray point at the other end either.
+func TestOrientationDropsLastPoint(t *testing.T) {
+	one := MustSegments("bc")
+	for _, o := range []Orientation{OrientationRotate180, OrientationMirrorX} {
+		physical := o.PhysicalDigits([8]byte{0: one, 7: one | SegDP})
+		if want := ([8]byte{0: o.Glyph(one), 7: o.Glyph(one)}); physical != want {
+			t.Errorf("%v physical digits % X, expected % X", o, physical, want)
+		}
+		// The spare point at the top left of logical digit 0
+		if logical := o.LogicalDigits([8]byte{7: SegDP}); logical != ([8]byte{}) {
+			t.Errorf("%v logical digits % X for the spare point", o, logical)
+		}
+	}
+	glyphs := OrientationRotate180.WideGlyphs(Segments14, [4]WideGlyph{3: WideSegDP})
+	if glyphs != ([4]WideGlyph{}) {
+		t.Errorf("wide glyphs % X", glyphs)
+	}
+}
+
+// Every other point survives the trip to the physical digits and back.
+func TestOrientationDigitsRoundTrip(t *testing.T) {
+	for o := OrientationNormal; o <= OrientationMirrorY; o++ {
+		for i := 0; i < 8; i++ {
+			for _, g := range []byte{0xFF, SegDP, MustSegments("abf")} {
+				var digits [8]byte
+				digits[i] = g
+				want := digits
+				if o.reversed() && i == 7 {
+					want[7] &^= SegDP
+				}
+				if got := o.LogicalDigits(o.PhysicalDigits(digits)); got != want {
+					t.Errorf("%v digit %d: % X came back as % X", o, i, digits, got)
+				}
+				if got := o.PhysicalDigits(o.LogicalDigits(digits)); got != want {
+					t.Errorf("%v physical digit %d: % X came back as % X", o, i, digits, got)
+				}
+			}
+		}
+	}
+}
+
+func TestOrientationLEDs(t *testing.T) {
+	leds := [8]bool{true, true, false, true}
+	reversed := [8]bool{4: true, 6: true, 7: true}
+	for o, want := range map[Orientation][8]bool{
+		OrientationNormal:    leds,
+		OrientationRotate180: reversed,
+		OrientationMirrorX:   reversed,
+		OrientationMirrorY:   leds,
+	} {
+		if got := o.LEDs(leds); got != want {
+			t.Errorf("%v LEDs %v, expected %v", o, got, want)
+		}
+		if got := o.LEDs(want); got != leds {
+			t.Errorf("%v LEDs back %v, expected %v", o, got, leds)
+		}
+	}
+}
+
+func TestOrientationButtons(t *testing.T) {
+	tests := []struct {
+		o        Orientation
+		layout   BoardLayout
+		logical  int
+		physical int
+	}{
+		{OrientationNormal, LayoutLED8KEY, 1, 1},
+		{OrientationRotate180, LayoutLED8KEY, 1, 6},
+		{OrientationMirrorX, LayoutLED8KEY, 0, 7},
+		{OrientationMirrorY, LayoutLED8KEY, 2, 2},
+		{OrientationNormal, LayoutDISP16KEY, 6, 6},
+		{OrientationRotate180, LayoutDISP16KEY, 1, 14}, // Row 0 col 1 -> row 3 col 2
+		{OrientationMirrorX, LayoutDISP16KEY, 4, 7},    // Row 1 col 0 -> row 1 col 3
+		{OrientationMirrorY, LayoutDISP16KEY, 4, 8},    // Row 1 col 0 -> row 2 col 0
+	}
+	for _, test := range tests {
+		if got := test.o.Button(test.layout, test.logical); got != test.physical {
+			t.Errorf("%v %v button %d is physical %d, expected %d", test.o, test.layout, test.logical, got, test.physical)
+		}
+		if got := test.o.Button(test.layout, test.physical); got != test.logical {
+			t.Errorf("%v %v physical button %d is logical %d, expected %d", test.o, test.layout, test.physical, got, test.logical)
+		}
+	}
+
+	keys := DISP16KEYKeys[:]
+	for o := OrientationNormal; o <= OrientationMirrorY; o++ {
+		logical := o.Keys(LayoutDISP16KEY, keys)
+		for i := range keys {
+			if logical[i] != keys[o.Button(LayoutDISP16KEY, i)] {
+				t.Errorf("%v key %d is %v", o, i, logical[i])
+			}
+		}
+	}
+}
+
+// A board mounted upside down shows the text the right way up and reads
+// its buttons in logical order.
+func TestOrientationBoard(t *testing.T) {
+	sim := NewTM1638Sim()
+	p := NewLED8KEY(sim.Pins())
+	p.Timing = TM1638Timing{}
+	p.Orientation = OrientationRotate180
+	if err := p.WriteString("1.2"); err != nil {
+		t.Fatal(err)
+	}
+	physical := LayoutLED8KEY.Digits(sim.Display())
+	want := [8]byte{6: OrientationRotate180.Glyph(MustSegments("abdeg")) | SegDP, 7: OrientationRotate180.Glyph(MustSegments("bc"))}
+	if physical != want {
+		t.Errorf("physical digits % X, expected % X", physical, want)
+	}
+	logical := p.Orientation.LogicalDigits(physical)
+	if got := p.DecodeDigits(logical[:2]); got != "1.2" {
+		t.Errorf("decoded as %q", got)
+	}
+
+	// The last point can't be shown, but DisplayText still has it
+	if err := p.WriteString("12345678."); err != nil {
+		t.Fatal(err)
+	}
+	if d := sim.Display(); d[0]&SegDP != 0 || d[14]&SegDP != 0 {
+		t.Errorf("stray point in % X", d)
+	}
+	if got := p.DisplayText(); got != "12345678." {
+		t.Errorf("DisplayText %q", got)
+	}
+
+	sim.SetKey(LED8KEYKeys[7], true) // Rightmost physical button
+	var buttons [8]bool
+	if err := p.ReadButtons(&buttons); err != nil {
+		t.Fatal(err)
+	}
+	if buttons != ([8]bool{true}) {
+		t.Errorf("buttons %v", buttons)
+	}
+}
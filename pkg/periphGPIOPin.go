@@ -0,0 +1,90 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"fmt"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+)
+
+// GPIOPin over a periph.io gpio.PinIO. The caller is responsible for
+// initializing periph's host drivers (host.Init()) before opening pins.
+//
+// periph sets the level and the direction in one call (Out), so we remember
+// the last written level and apply it whenever the pin is switched to output.
+// That is what the driver's open-drain emulation on DIO relies on. The Pi's
+// GPIO has no open-drain hardware, so there is no OpenDrain method.
+type PeriphGPIOPin struct {
+	Pin   gpio.PinIO
+	Pull  gpio.Pull // Bias applied when the pin is switched to input
+	level gpio.Level
+	isOut bool
+}
+
+// Open a periph.io pin by name, e.g. "GPIO17".
+//   - name = the periph pin name
+//   - pull = the bias to apply whenever the pin is an input
+func NewPeriphGPIOPin(name string, pull gpio.Pull) (*PeriphGPIOPin, error) {
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("No GPIO pin named '%s'", name)
+	}
+	return &PeriphGPIOPin{Pin: p, Pull: pull}, nil
+}
+
+// Open the three TM1638 pins by name. DIO gets the internal pull-up since
+// the driver simulates open-drain on it. STROBE and CLK are always driven.
+func NewPeriphTM1638Pins(nameSTROBE string, nameCLK string, nameDIO string) (*PeriphGPIOPin, *PeriphGPIOPin, *PeriphGPIOPin, error) {
+	strobe, err := NewPeriphGPIOPin(nameSTROBE, gpio.Float)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	clk, err := NewPeriphGPIOPin(nameCLK, gpio.Float)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dio, err := NewPeriphGPIOPin(nameDIO, gpio.PullUp)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return strobe, clk, dio, nil
+}
+
+func (x *PeriphGPIOPin) Write(state bool) error {
+	x.level = gpio.Level(state)
+	if x.isOut {
+		return x.Pin.Out(x.level)
+	}
+	return nil
+}
+
+func (x *PeriphGPIOPin) Read() (bool, error) {
+	return x.Pin.Read() == gpio.High, nil
+}
+
+func (x *PeriphGPIOPin) Input() error {
+	x.isOut = false
+	return x.Pin.In(x.Pull, gpio.NoEdge)
+}
+
+func (x *PeriphGPIOPin) Output() error {
+	x.isOut = true
+	return x.Pin.Out(x.level)
+}
+
+func (x *PeriphGPIOPin) SetBias(bias GPIOBias) error {
+	switch bias {
+	case BiasPullUp:
+		x.Pull = gpio.PullUp
+	case BiasPullDown:
+		x.Pull = gpio.PullDown
+	default:
+		x.Pull = gpio.Float
+	}
+	if x.isOut {
+		return nil // Applied on the next Input
+	}
+	return x.Pin.In(x.Pull, gpio.NoEdge)
+}
@@ -0,0 +1,142 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+/*
+	The pigpio daemon (pigpiod) socket protocol.
+
+	Every command is 16 bytes: four little-endian uint32 values.
+	  cmd, p1, p2, p3
+
+	Every response is 16 bytes: the first three values echoed back and a
+	signed result. A negative result is a pigpio error code.
+	  cmd, p1, p2, res
+
+	We only need a handful of the commands:
+	  0  MODES  p1=gpio  p2=mode (0 input, 1 output)
+	  2  PUD    p1=gpio  p2=pud  (0 off, 1 down, 2 up)
+	  3  READ   p1=gpio          res=level
+	  4  WRITE  p1=gpio  p2=level
+*/
+
+const (
+	pigpioCmdMODES = 0
+	pigpioCmdPUD   = 2
+	pigpioCmdREAD  = 3
+	pigpioCmdWRITE = 4
+
+	pigpioModeInput  = 0
+	pigpioModeOutput = 1
+
+	pigpioPudOff  = 0
+	pigpioPudDown = 1
+	pigpioPudUp   = 2
+
+	// The daemon's default port
+	PigpioDefaultPort = 8888
+)
+
+// A connection to a pigpio daemon. One client can be shared by any number of
+// pins. Commands are serialized on the connection.
+type PigpioClient struct {
+	conn net.Conn
+	lock sync.Mutex
+	buf  [16]byte
+}
+
+// Connect to a pigpio daemon, e.g. "raspberrypi.local:8888".
+func DialPigpio(address string) (*PigpioClient, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &PigpioClient{conn: conn}, nil
+}
+
+// Close the connection to the daemon.
+func (x *PigpioClient) Close() error {
+	return x.conn.Close()
+}
+
+// Send one command and wait for its response. Returns the (non-negative)
+// result or an error for a failed connection or a negative pigpio result.
+func (x *PigpioClient) Command(cmd uint32, p1 uint32, p2 uint32) (uint32, error) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	binary.LittleEndian.PutUint32(x.buf[0:], cmd)
+	binary.LittleEndian.PutUint32(x.buf[4:], p1)
+	binary.LittleEndian.PutUint32(x.buf[8:], p2)
+	binary.LittleEndian.PutUint32(x.buf[12:], 0)
+	_, err := x.conn.Write(x.buf[:])
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.ReadFull(x.conn, x.buf[:])
+	if err != nil {
+		return 0, err
+	}
+	res := int32(binary.LittleEndian.Uint32(x.buf[12:]))
+	if res < 0 {
+		return 0, fmt.Errorf("pigpio command %d on gpio %d failed with %d", cmd, p1, res)
+	}
+	return uint32(res), nil
+}
+
+// GPIOPin driven remotely through a pigpio daemon. Gpio is the BCM number.
+// The Pi's GPIO has no open-drain hardware, so there is no OpenDrain method:
+// the driver simulates it on DIO by switching the direction.
+type PigpioGPIOPin struct {
+	Client *PigpioClient
+	Gpio   uint32
+}
+
+// Apply the internal pull-up to the pin. The driver does this on DIO
+// automatically through SetBias.
+func (x PigpioGPIOPin) PullUp() error {
+	return x.SetBias(BiasPullUp)
+}
+
+func (x PigpioGPIOPin) SetBias(bias GPIOBias) error {
+	var pud uint32 = pigpioPudOff
+	switch bias {
+	case BiasPullUp:
+		pud = pigpioPudUp
+	case BiasPullDown:
+		pud = pigpioPudDown
+	}
+	_, err := x.Client.Command(pigpioCmdPUD, x.Gpio, pud)
+	return err
+}
+
+func (x PigpioGPIOPin) Write(state bool) error {
+	var level uint32 = 0
+	if state {
+		level = 1
+	}
+	_, err := x.Client.Command(pigpioCmdWRITE, x.Gpio, level)
+	return err
+}
+
+func (x PigpioGPIOPin) Read() (bool, error) {
+	v, err := x.Client.Command(pigpioCmdREAD, x.Gpio, 0)
+	return v == 1, err
+}
+
+func (x PigpioGPIOPin) Input() error {
+	_, err := x.Client.Command(pigpioCmdMODES, x.Gpio, pigpioModeInput)
+	return err
+}
+
+func (x PigpioGPIOPin) Output() error {
+	_, err := x.Client.Command(pigpioCmdMODES, x.Gpio, pigpioModeOutput)
+	return err
+}
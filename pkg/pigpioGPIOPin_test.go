@@ -0,0 +1,112 @@
+//go:build !tinygo
+
+package pkg
+
+import "testing"
+
+// A stand-in daemon and a client connected to it.
+func newPigpioStandInClient(t *testing.T) (*PigpioStandIn, *PigpioClient) {
+	t.Helper()
+	standIn, err := ListenPigpioStandIn("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { standIn.Close() })
+	client, err := DialPigpio(standIn.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return standIn, client
+}
+
+func expectPigpioState(t *testing.T, standIn *PigpioStandIn, gpio uint32, isOutput bool, level bool) {
+	t.Helper()
+	gotOutput, gotLevel := standIn.State(gpio)
+	if gotOutput != isOutput || gotLevel != level {
+		t.Errorf("gpio %d is output=%v level=%v, expected output=%v level=%v", gpio, gotOutput, gotLevel, isOutput, level)
+	}
+}
+
+func TestPigpioPin(t *testing.T) {
+	standIn, client := newPigpioStandInClient(t)
+	pin := PigpioGPIOPin{Client: client, Gpio: 17}
+
+	if err := pin.Input(); err != nil {
+		t.Fatal(err)
+	}
+	expectPigpioState(t, standIn, 17, false, false)
+
+	if err := pin.SetBias(BiasPullUp); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := pin.Read(); err != nil || !v {
+		t.Errorf("pulled up input read %v, %v", v, err)
+	}
+
+	standIn.SetExternal(17, false)
+	if v, err := pin.Read(); err != nil || v {
+		t.Errorf("input driven low read %v, %v", v, err)
+	}
+	standIn.ReleaseExternal(17)
+
+	// Writing switches the pin to output, like the real daemon
+	if err := pin.Write(false); err != nil {
+		t.Fatal(err)
+	}
+	expectPigpioState(t, standIn, 17, true, false)
+	if err := pin.Write(true); err != nil {
+		t.Fatal(err)
+	}
+	expectPigpioState(t, standIn, 17, true, true)
+}
+
+func TestPigpioPinErrors(t *testing.T) {
+	_, client := newPigpioStandInClient(t)
+	if err := (PigpioGPIOPin{Client: client, Gpio: 99}).Output(); err == nil {
+		t.Error("no error for a bad gpio")
+	}
+	if _, err := client.Command(99, 17, 0); err == nil {
+		t.Error("no error for an unknown command")
+	}
+}
+
+func TestPigpioTM1638Setup(t *testing.T) {
+	standIn, client := newPigpioStandInClient(t)
+	_, err := NewTM1638E(PigpioGPIOPin{Client: client, Gpio: 17}, PigpioGPIOPin{Client: client, Gpio: 27}, PigpioGPIOPin{Client: client, Gpio: 22})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPigpioState(t, standIn, 17, true, true)  // STROBE idles high
+	expectPigpioState(t, standIn, 27, true, true)  // CLK idles high
+	expectPigpioState(t, standIn, 22, false, true) // DIO released and pulled up
+
+	_, err = NewTM1638E(PigpioGPIOPin{Client: client, Gpio: 99}, PigpioGPIOPin{Client: client, Gpio: 27}, PigpioGPIOPin{Client: client, Gpio: 22})
+	if err == nil {
+		t.Error("no setup error for a bad STROBE gpio")
+	}
+}
+
+func TestPigpioStandInClose(t *testing.T) {
+	standIn, err := ListenPigpioStandIn("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialPigpio(standIn.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	pin := PigpioGPIOPin{Client: client, Gpio: 17}
+	if err := pin.Output(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close waits for the open connection's handler
+	if err := standIn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pin.Output(); err == nil {
+		t.Error("command on a closed stand-in worked")
+	}
+}
@@ -0,0 +1,205 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// A tiny stand-in for the pigpio daemon. It speaks the same socket protocol
+// (MODES, PUD, READ and WRITE only) and keeps the pin states in memory. Use
+// it to exercise PigpioGPIOPin without a Pi.
+//
+// An input pin reads the level set with SetExternal. If nothing drives it,
+// it reads its pull: high for pull-up and low otherwise.
+type PigpioStandIn struct {
+	listener net.Listener
+	lock     sync.Mutex
+	pins     map[uint32]*pigpioStandInPin
+	conns    map[net.Conn]bool // Open client connections
+	closed   bool
+	wait     sync.WaitGroup // The accept loop and the connection handlers
+
+	// Called (with the lock held) after any MODES or WRITE command
+	OnChange func(gpio uint32, isOutput bool, level bool)
+}
+
+type pigpioStandInPin struct {
+	isOutput bool
+	pud      uint32
+	level    bool // Output latch
+	driven   bool // Externally driven (input only)
+	external bool // External level
+}
+
+// Start a stand-in daemon on the given address. Use "127.0.0.1:0" to pick
+// any free port and Addr() to find it.
+func ListenPigpioStandIn(address string) (*PigpioStandIn, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	ret := &PigpioStandIn{listener: listener, pins: map[uint32]*pigpioStandInPin{}, conns: map[net.Conn]bool{}}
+	ret.wait.Add(1)
+	go ret.serve()
+	return ret, nil
+}
+
+// The address the stand-in is listening on.
+func (x *PigpioStandIn) Addr() string {
+	return x.listener.Addr().String()
+}
+
+// Stop accepting connections, close the open ones and wait for their
+// handlers to finish.
+func (x *PigpioStandIn) Close() error {
+	err := x.listener.Close()
+	x.lock.Lock()
+	x.closed = true
+	for conn := range x.conns {
+		conn.Close()
+	}
+	x.lock.Unlock()
+	x.wait.Wait()
+	return err
+}
+
+// Drive an input pin from the outside (like the TM1638 driving DIO).
+func (x *PigpioStandIn) SetExternal(gpio uint32, level bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	p := x.pin(gpio)
+	p.driven = true
+	p.external = level
+}
+
+// Stop driving an input pin from the outside.
+func (x *PigpioStandIn) ReleaseExternal(gpio uint32) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.pin(gpio).driven = false
+}
+
+// The current mode and level of a pin as seen on the wire.
+func (x *PigpioStandIn) State(gpio uint32) (isOutput bool, level bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	p := x.pin(gpio)
+	return p.isOutput, p.read()
+}
+
+func (x *PigpioStandIn) pin(gpio uint32) *pigpioStandInPin {
+	p, ok := x.pins[gpio]
+	if !ok {
+		p = &pigpioStandInPin{}
+		x.pins[gpio] = p
+	}
+	return p
+}
+
+func (p *pigpioStandInPin) read() bool {
+	if p.isOutput {
+		return p.level
+	}
+	if p.driven {
+		return p.external
+	}
+	return p.pud == pigpioPudUp
+}
+
+func (x *PigpioStandIn) serve() {
+	defer x.wait.Done()
+	for {
+		conn, err := x.listener.Accept()
+		if err != nil {
+			return
+		}
+		x.lock.Lock()
+		if x.closed {
+			x.lock.Unlock()
+			conn.Close()
+			return
+		}
+		x.conns[conn] = true
+		x.wait.Add(1)
+		x.lock.Unlock()
+		go x.handle(conn)
+	}
+}
+
+func (x *PigpioStandIn) handle(conn net.Conn) {
+	defer x.wait.Done()
+	defer func() {
+		x.lock.Lock()
+		delete(x.conns, conn)
+		x.lock.Unlock()
+		conn.Close()
+	}()
+	var buf [16]byte
+	for {
+		_, err := io.ReadFull(conn, buf[:])
+		if err != nil {
+			return
+		}
+		cmd := binary.LittleEndian.Uint32(buf[0:])
+		gpio := binary.LittleEndian.Uint32(buf[4:])
+		p2 := binary.LittleEndian.Uint32(buf[8:])
+		res := x.execute(cmd, gpio, p2)
+		binary.LittleEndian.PutUint32(buf[12:], uint32(res))
+		_, err = conn.Write(buf[:])
+		if err != nil {
+			return
+		}
+	}
+}
+
+// pigpio error codes we report
+const (
+	pigpioBadGpio  = -3
+	pigpioBadMode  = -4
+	pigpioBadLevel = -5
+	pigpioBadPud   = -6
+	pigpioUnknown  = -88
+)
+
+func (x *PigpioStandIn) execute(cmd uint32, gpio uint32, p2 uint32) int32 {
+	if gpio > 53 {
+		return pigpioBadGpio
+	}
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	p := x.pin(gpio)
+	switch cmd {
+	case pigpioCmdMODES:
+		if p2 != pigpioModeInput && p2 != pigpioModeOutput {
+			return pigpioBadMode
+		}
+		p.isOutput = p2 == pigpioModeOutput
+	case pigpioCmdPUD:
+		if p2 > pigpioPudUp {
+			return pigpioBadPud
+		}
+		p.pud = p2
+		return 0
+	case pigpioCmdREAD:
+		if p.read() {
+			return 1
+		}
+		return 0
+	case pigpioCmdWRITE:
+		if p2 > 1 {
+			return pigpioBadLevel
+		}
+		p.level = p2 == 1
+		p.isOutput = true // Like gpioWrite, writing makes the pin an output
+	default:
+		return pigpioUnknown
+	}
+	if x.OnChange != nil {
+		x.OnChange(gpio, p.isOutput, p.level)
+	}
+	return 0
+}
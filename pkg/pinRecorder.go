@@ -0,0 +1,267 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+/*
+	Records the waveforms on the bus by wrapping the pins handed to the
+	driver. Every Write, Input, Output and Read is timestamped and can be
+	exported as a Value Change Dump (VCD) file for GTKWave or PulseView.
+
+	Each wrapped pin becomes two signals:
+	  NAME         what we put on the wire: 0, 1 or z (released)
+	  NAME_sample  the value of the last Read (only if the pin is read)
+
+	  rec := pkg.NewPinRecorder()
+	  p := pkg.NewLED8KEY(rec.Wrap("STROBE", strobe), rec.Wrap("CLK", clk), rec.Wrap("DIO", dio))
+	  ...
+	  rec.WriteVCD(file)
+*/
+
+// One change on one signal.
+type PinEvent struct {
+	Time   time.Duration // Since the recorder was created
+	Signal string
+	Value  byte // '0', '1' or 'z'
+}
+
+type PinRecorder struct {
+	lock    sync.Mutex
+	start   time.Time
+	signals []string
+	events  []pinRecorderEvent
+}
+
+type pinRecorderEvent struct {
+	time   time.Duration
+	signal int
+	value  byte
+}
+
+// Start recording. Timestamps are relative to now.
+func NewPinRecorder() *PinRecorder {
+	return &PinRecorder{start: time.Now()}
+}
+
+// Wrap a pin so that everything done to it is recorded under the given name.
+// The wrapper has the same optional open-drain and bias capabilities as the
+// pin, so the driver makes the same choices with and without recording.
+func (r *PinRecorder) Wrap(name string, pin CheckedGPIOPin) CheckedGPIOPin {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ret := &recordedPin{recorder: r, pin: pin, wire: len(r.signals), sample: len(r.signals) + 1}
+	r.signals = append(r.signals, name, name+"_sample")
+
+	_, openDrain := pin.(OpenDrainGPIOPin)
+	_, biased := pin.(BiasedGPIOPin)
+	switch {
+	case openDrain && biased:
+		return recordedOpenDrainBiasedPin{ret}
+	case openDrain:
+		return recordedOpenDrainPin{ret}
+	case biased:
+		return recordedBiasedPin{ret}
+	}
+	return ret
+}
+
+// All the changes recorded so far, oldest first.
+func (r *PinRecorder) Events() []PinEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ret := make([]PinEvent, len(r.events))
+	for i, e := range r.events {
+		ret[i] = PinEvent{e.time, r.signals[e.signal], e.value}
+	}
+	return ret
+}
+
+// Forget everything recorded so far and restart the clock.
+func (r *PinRecorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = nil
+	r.start = time.Now()
+}
+
+func (r *PinRecorder) record(signal int, value byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, pinRecorderEvent{time.Since(r.start), signal, value})
+}
+
+// The VCD identifier for a signal: printable characters from '!'.
+func vcdIdentifier(signal int) string {
+	ret := ""
+	for {
+		ret += string(rune('!' + signal%94))
+		signal /= 94
+		if signal == 0 {
+			return ret
+		}
+		signal--
+	}
+}
+
+// Write the recording as a VCD file with a 1ns timescale. Signals that
+// never changed (like the samples of a pin that is never read) are left out.
+func (r *PinRecorder) WriteVCD(w io.Writer) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Give identifiers to the signals that have events
+	ids := make([]string, len(r.signals))
+	count := 0
+	for _, e := range r.events {
+		if ids[e.signal] == "" {
+			ids[e.signal] = "?" // Placeholder until we number them in order
+		}
+	}
+	for i := range ids {
+		if ids[i] != "" {
+			ids[i] = vcdIdentifier(count)
+			count++
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "$date %s $end\n", r.start.Format(time.RFC1123))
+	fmt.Fprintf(b, "$version go-led8key PinRecorder $end\n")
+	fmt.Fprintf(b, "$timescale 1ns $end\n")
+	fmt.Fprintf(b, "$scope module tm1638 $end\n")
+	for i, name := range r.signals {
+		if ids[i] != "" {
+			fmt.Fprintf(b, "$var wire 1 %s %s $end\n", ids[i], name)
+		}
+	}
+	fmt.Fprintf(b, "$upscope $end\n")
+	fmt.Fprintf(b, "$enddefinitions $end\n")
+
+	// Everything is unknown until its first event
+	fmt.Fprintf(b, "$dumpvars\n")
+	for i := range r.signals {
+		if ids[i] != "" {
+			fmt.Fprintf(b, "x%s\n", ids[i])
+		}
+	}
+	fmt.Fprintf(b, "$end\n")
+
+	last := time.Duration(-1)
+	for _, e := range r.events {
+		if e.time != last {
+			fmt.Fprintf(b, "#%d\n", e.time.Nanoseconds())
+			last = e.time
+		}
+		fmt.Fprintf(b, "%c%s\n", e.value, ids[e.signal])
+	}
+	return b.Flush()
+}
+
+// A pin that reports to a PinRecorder.
+type recordedPin struct {
+	recorder  *PinRecorder
+	pin       CheckedGPIOPin
+	wire      int // Signal index for what we drive
+	sample    int // Signal index for what we read
+	isOutput  bool
+	openDrain bool
+	latch     bool
+}
+
+// Record what we are putting on the wire now.
+func (x *recordedPin) recordWire() {
+	value := byte('z')
+	if x.openDrain {
+		if !x.latch {
+			value = '0'
+		}
+	} else if x.isOutput {
+		value = '0'
+		if x.latch {
+			value = '1'
+		}
+	}
+	x.recorder.record(x.wire, value)
+}
+
+func (x *recordedPin) Write(state bool) error {
+	err := x.pin.Write(state)
+	x.latch = state
+	x.recordWire()
+	return err
+}
+
+func (x *recordedPin) Read() (bool, error) {
+	ret, err := x.pin.Read()
+	value := byte('0')
+	if ret {
+		value = '1'
+	}
+	x.recorder.record(x.sample, value)
+	return ret, err
+}
+
+func (x *recordedPin) Input() error {
+	err := x.pin.Input()
+	x.isOutput = false
+	x.recordWire()
+	return err
+}
+
+func (x *recordedPin) Output() error {
+	err := x.pin.Output()
+	x.isOutput = true
+	x.recordWire()
+	return err
+}
+
+// Pass OpenDrain through to a pin that has it.
+func (x *recordedPin) openDrainPin() error {
+	err := x.pin.(OpenDrainGPIOPin).OpenDrain()
+	if err == nil {
+		x.openDrain = true
+		x.recordWire()
+	}
+	return err
+}
+
+// Pass SetBias through to a pin that has it.
+func (x *recordedPin) setBias(bias GPIOBias) error {
+	return x.pin.(BiasedGPIOPin).SetBias(bias)
+}
+
+// A recorded pin that can be open-drain.
+type recordedOpenDrainPin struct {
+	*recordedPin
+}
+
+func (x recordedOpenDrainPin) OpenDrain() error {
+	return x.openDrainPin()
+}
+
+// A recorded pin with a bias.
+type recordedBiasedPin struct {
+	*recordedPin
+}
+
+func (x recordedBiasedPin) SetBias(bias GPIOBias) error {
+	return x.setBias(bias)
+}
+
+// A recorded pin with both.
+type recordedOpenDrainBiasedPin struct {
+	*recordedPin
+}
+
+func (x recordedOpenDrainBiasedPin) OpenDrain() error {
+	return x.openDrainPin()
+}
+
+func (x recordedOpenDrainBiasedPin) SetBias(bias GPIOBias) error {
+	return x.setBias(bias)
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import "testing"
+
+// Test pins with and without the optional capabilities.
+type plainTestPin struct{}
+
+func (plainTestPin) Write(bool) error    { return nil }
+func (plainTestPin) Read() (bool, error) { return false, nil }
+func (plainTestPin) Input() error        { return nil }
+func (plainTestPin) Output() error       { return nil }
+
+type openDrainTestPin struct{ plainTestPin }
+
+func (openDrainTestPin) OpenDrain() error { return nil }
+
+type biasedTestPin struct{ plainTestPin }
+
+func (biasedTestPin) SetBias(GPIOBias) error { return nil }
+
+type openDrainBiasedTestPin struct{ plainTestPin }
+
+func (openDrainBiasedTestPin) OpenDrain() error       { return nil }
+func (openDrainBiasedTestPin) SetBias(GPIOBias) error { return nil }
+
+func TestPinRecorderCapabilities(t *testing.T) {
+	tests := []struct {
+		pin       CheckedGPIOPin
+		openDrain bool
+		biased    bool
+	}{
+		{plainTestPin{}, false, false},
+		{openDrainTestPin{}, true, false},
+		{biasedTestPin{}, false, true},
+		{openDrainBiasedTestPin{}, true, true},
+	}
+	r := NewPinRecorder()
+	for _, test := range tests {
+		wrapped := r.Wrap("DIO", test.pin)
+		_, openDrain := wrapped.(OpenDrainGPIOPin)
+		_, biased := wrapped.(BiasedGPIOPin)
+		if openDrain != test.openDrain || biased != test.biased {
+			t.Errorf("%T wrapped: open-drain %v, biased %v. Expected %v, %v", test.pin, openDrain, biased, test.openDrain, test.biased)
+		}
+	}
+}
+
+// The driver emulates open-drain on a recorded pin that can't do it.
+func TestPinRecorderEmulatedOpenDrain(t *testing.T) {
+	sim := NewTM1638Sim()
+	strobe, clk, dio := sim.Pins()
+	r := NewPinRecorder()
+	chip := NewTM1638(r.Wrap("STROBE", strobe), r.Wrap("CLK", clk), r.Wrap("DIO", dio))
+	chip.Timing = TM1638Timing{}
+	if chip.openDrain {
+		t.Error("driver chose open-drain for a pin without it")
+	}
+	if err := chip.WriteData(0, []byte{0x5B}); err != nil {
+		t.Fatal(err)
+	}
+	if d := sim.Display(); d[0] != 0x5B {
+		t.Errorf("display RAM % X", d)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !tinygo
+
 package pkg
 
 import "github.com/stianeikeland/go-rpio"
@@ -6,22 +8,40 @@ type RPiGPIOPin struct {
 	RpiPin rpio.Pin
 }
 
-func (x RPiGPIOPin) Write(state bool) {
+// rpio works on memory-mapped registers. None of its pin operations can
+// fail once rpio.Open succeeds.
+
+func (x RPiGPIOPin) Write(state bool) error {
 	if state {
 		x.RpiPin.Write(1)
 	} else {
 		x.RpiPin.Write(0)
 	}
+	return nil
 }
 
-func (x RPiGPIOPin) Read() bool {
-	return x.RpiPin.Read() == rpio.High
+func (x RPiGPIOPin) Read() (bool, error) {
+	return x.RpiPin.Read() == rpio.High, nil
 }
 
-func (x RPiGPIOPin) Input() {
+func (x RPiGPIOPin) Input() error {
 	x.RpiPin.Input()
+	return nil
 }
 
-func (x RPiGPIOPin) Output() {
+func (x RPiGPIOPin) Output() error {
 	x.RpiPin.Output()
+	return nil
+}
+
+func (x RPiGPIOPin) SetBias(bias GPIOBias) error {
+	switch bias {
+	case BiasPullUp:
+		x.RpiPin.PullUp()
+	case BiasPullDown:
+		x.RpiPin.PullDown()
+	default:
+		x.RpiPin.PullOff()
+	}
+	return nil
 }
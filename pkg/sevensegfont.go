@@ -1,6 +1,11 @@
 package pkg
 
-import "fmt"
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
 
 type SevenSegFont struct {
 	font map[int]byte
@@ -17,31 +22,31 @@ func (x *SevenSegFont) ResetFont() {
 	// Limited font mapping from string characters to bit patterns. The user
 	// can extend/change this mapping as needed.
 	x.font = map[int]byte{
-		' ': 0b0_0000000,
-		'0': 0b0_0111111,
-		'1': 0b0_0000110,
-		'2': 0b0_1011011,
-		'3': 0b0_1001111,
-		'4': 0b0_1100110,
-		'5': 0b0_1101101,
-		'6': 0b0_1111101,
-		'7': 0b0_0000111,
-		'8': 0b0_1111111,
-		'9': 0b0_1101111,
-		'.': 0b1_0000000, // The PrintString will attempt to combine
-		'-': 0b0_1000000, // Minus sign
+		' ': 0,
+		'0': MustSegments("abcdef"),
+		'1': MustSegments("bc"),
+		'2': MustSegments("abdeg"),
+		'3': MustSegments("abcdg"),
+		'4': MustSegments("bcfg"),
+		'5': MustSegments("acdfg"),
+		'6': MustSegments("acdefg"),
+		'7': MustSegments("abc"),
+		'8': MustSegments("abcdefg"),
+		'9': MustSegments("abcdfg"),
+		'.': MustSegments("dp"), // The PrintString will attempt to combine
+		'-': MustSegments("g"),  // Minus sign
 		// Useful for hex
-		'A': 0b0_1110111,
-		'B': 0b0_1111100,
-		'C': 0b0_0111001,
-		'D': 0b0_1011110,
-		'E': 0b0_1111001,
-		'F': 0b0_1110001,
+		'A': MustSegments("abcefg"),
+		'B': MustSegments("cdefg"),
+		'C': MustSegments("adef"),
+		'D': MustSegments("bcdeg"),
+		'E': MustSegments("adefg"),
+		'F': MustSegments("aefg"),
 		// Some random letters for example
-		'H': 0b0_1110110,
-		'i': 0b0_0000100,
-		'L': 0b0_0111000,
-		'o': 0b0_1011100,
+		'H': MustSegments("bcefg"),
+		'i': MustSegments("c"),
+		'L': MustSegments("def"),
+		'o': MustSegments("cdeg"),
 	}
 }
 
@@ -57,7 +62,44 @@ func (x *SevenSegFont) GetMutableFont() map[int]byte {
 // maxDigits = the maximum digits to create
 // outDigits = the returned slice of digit data
 func (x *SevenSegFont) BuildDigits(chars string, numDigits int, outDigits []byte) error {
+	if numDigits < 0 || numDigits > len(outDigits) {
+		return fmt.Errorf("Can't build %d digits into %d", numDigits, len(outDigits))
+	}
+	return buildGlyphs(x.font, SegDP, chars, outDigits[:numDigits])
+}
+
+// All the characters in the font with exactly this segment pattern, lowest
+// first.
+func (x *SevenSegFont) Candidates(pattern byte) []int {
+	return fontCandidates(x.font, pattern)
+}
+
+// Find the character for a digit's segment pattern. A pattern that is a
+// character plus the decimal point decodes to the character with dot set.
+// If nothing matches exactly, the character with the fewest differing
+// segments is returned and exact is false. Ties go to the lowest character.
+func (x *SevenSegFont) DecodeDigit(pattern byte) (char int, dot bool, exact bool) {
+	return decodeGlyph(x.font, SegDP, pattern)
+}
+
+// Turn digits back into text, the reverse of BuildDigits. A decimal point
+// becomes a '.' after its character. Patterns with no exact match in the
+// font become '?'.
+func (x *SevenSegFont) DecodeDigits(digits []byte) string {
+	return decodeGlyphs(x.font, SegDP, digits)
+}
+
+/*
+	The font work is the same for every glyph size, so SevenSegFont (a byte
+	per digit) and WideFont (a WideGlyph per digit) share these. dp is the
+	glyph's decimal point bit.
+*/
+
+type fontGlyph interface {
+	~uint8 | ~uint32
+}
 
+func buildGlyphs[G fontGlyph](font map[int]G, dp G, chars string, outGlyphs []G) error {
 	previous := -1 // No previous-position yet
 	pos := 0       // Next digit to fill
 
@@ -65,23 +107,23 @@ func (x *SevenSegFont) BuildDigits(chars string, numDigits int, outDigits []byte
 		if chars[i] == '.' {
 			// If this is a period, we'll try to merge it with the previous digit
 			if previous >= 0 {
-				outDigits[previous] |= 0b1_0000000
+				outGlyphs[previous] |= dp
 				previous = -1
 				continue // No new digit ... continue with next character
 			}
 		}
 		// Lookup the segment bit pattern
-		value, exist := x.font[int(chars[i])]
+		value, exist := font[int(chars[i])]
 		if !exist {
 			return fmt.Errorf("No font mapping for '%c' in '%s'.", chars[i], chars)
 		}
-		if i > numDigits {
-			return fmt.Errorf("Exceeded number of %d digits", numDigits)
+		if pos >= len(outGlyphs) {
+			return fmt.Errorf("Exceeded number of %d digits", len(outGlyphs))
 		}
 		// Add the value
-		outDigits[pos] = value
+		outGlyphs[pos] = value
+		previous = pos
 		pos++
-		previous = i
 		if chars[i] == '.' {
 			// Decimal points cannot be merged to dots
 			previous = -1
@@ -89,10 +131,58 @@ func (x *SevenSegFont) BuildDigits(chars string, numDigits int, outDigits []byte
 
 	}
 
-	for i := pos; i < numDigits; i++ {
+	for i := pos; i < len(outGlyphs); i++ {
 		// Blank untouched digits
-		outDigits[i] = 0
+		outGlyphs[i] = 0
 	}
 
 	return nil
 }
+
+func fontCandidates[G fontGlyph](font map[int]G, glyph G) []int {
+	ret := []int{}
+	for c, g := range font {
+		if g == glyph {
+			ret = append(ret, c)
+		}
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+func decodeGlyph[G fontGlyph](font map[int]G, dp G, glyph G) (char int, dot bool, exact bool) {
+	// The whole glyph first (like a lone '.')
+	if c := fontCandidates(font, glyph); len(c) > 0 {
+		return c[0], false, true
+	}
+	dot = glyph&dp != 0
+	glyph &^= dp
+	if c := fontCandidates(font, glyph); len(c) > 0 {
+		return c[0], dot, true
+	}
+
+	char = -1
+	best := 65
+	for c, g := range font {
+		d := bits.OnesCount64(uint64((g ^ glyph) &^ dp))
+		if g&dp == 0 && (d < best || (d == best && c < char)) {
+			char, best = c, d
+		}
+	}
+	return char, dot, false
+}
+
+func decodeGlyphs[G fontGlyph](font map[int]G, dp G, glyphs []G) string {
+	var ret strings.Builder
+	for _, g := range glyphs {
+		char, dot, exact := decodeGlyph(font, dp, g)
+		if !exact || char < 0 || char > 0xFF {
+			char = '?'
+		}
+		ret.WriteByte(byte(char))
+		if dot {
+			ret.WriteByte('.')
+		}
+	}
+	return ret.String()
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import "testing"
+
+// BuildDigits as it was before the font work was shared with WideFont:
+// points merge into the digit before them (but never into another point),
+// untouched digits are blanked and only numDigits digits are written.
+func TestBuildDigits(t *testing.T) {
+	one, two, dot := MustSegments("bc"), MustSegments("abdeg"), MustSegments("dp")
+	tests := []struct {
+		chars string
+		want  [4]byte
+	}{
+		{"1.2", [4]byte{one | dot, two}},
+		{".1", [4]byte{dot, one}},
+		{"1..2", [4]byte{one | dot, dot, two}},
+		{"1.2.1.2.", [4]byte{one | dot, two | dot, one | dot, two | dot}},
+		{"", [4]byte{}},
+	}
+	font := NewSevenSegFont()
+	for _, test := range tests {
+		digits := [4]byte{0xFF, 0xFF, 0xFF, 0xFF}
+		if err := font.BuildDigits(test.chars, 4, digits[:]); err != nil {
+			t.Errorf("%q: %v", test.chars, err)
+		} else if digits != test.want {
+			t.Errorf("%q built % X, expected % X", test.chars, digits, test.want)
+		}
+	}
+
+	digits := [4]byte{0xFF, 0xFF, 0xFF, 0xFF}
+	if err := font.BuildDigits("1", 2, digits[:]); err != nil {
+		t.Fatal(err)
+	}
+	if digits != ([4]byte{one, 0, 0xFF, 0xFF}) {
+		t.Errorf("wrote past numDigits: % X", digits)
+	}
+
+	for _, chars := range []string{"123", "1.2.3.", "..."} {
+		if err := font.BuildDigits(chars, 2, digits[:]); err == nil {
+			t.Errorf("no error for %q on 2 digits", chars)
+		}
+	}
+	if err := font.BuildDigits("1", 1, digits[:]); err != nil {
+		t.Error(err)
+	}
+	if err := font.BuildDigits("#", 4, digits[:]); err == nil {
+		t.Error("no error for a character not in the font")
+	}
+	// Used to panic
+	if err := font.BuildDigits("1", 5, digits[:]); err == nil {
+		t.Error("no error for more digits than the slice holds")
+	}
+}
+
+func TestDecodeDigit(t *testing.T) {
+	tests := []struct {
+		segments string
+		char     int
+		dot      bool
+		exact    bool
+	}{
+		{"bcfg", '4', false, true},
+		{"bcfgdp", '4', true, true},
+		{"", ' ', false, true},
+		{"dp", '.', false, true},      // A lone point is the '.' glyph, not a blank with a dot
+		{"abcf", '7', false, false},   // A 7 with one extra segment
+		{"abcfdp", '7', true, false},  // The point still counts
+		{"abcdeg", '2', false, false}, // One off from 2, 3, 8 and D: the lowest wins
+	}
+	font := NewSevenSegFont()
+	for _, test := range tests {
+		char, dot, exact := font.DecodeDigit(MustSegments(test.segments))
+		if char != test.char || dot != test.dot || exact != test.exact {
+			t.Errorf("%q decoded as %q %v %v, expected %q %v %v",
+				test.segments, char, dot, exact, test.char, test.dot, test.exact)
+		}
+	}
+}
+
+func TestDecodeDigits(t *testing.T) {
+	one, two, dot := MustSegments("bc"), MustSegments("abdeg"), MustSegments("dp")
+	tests := []struct {
+		digits []byte
+		want   string
+	}{
+		{[]byte{one, two}, "12"},
+		{[]byte{one | dot, two}, "1.2"}, // The point merged into the 1
+		{[]byte{one | dot, dot, two}, "1..2"},
+		{[]byte{dot, one}, ".1"},
+		{[]byte{one, MustSegments("abcf"), two}, "1?2"}, // No exact match
+		{[]byte{MustSegments("abcf") | dot}, "?."},
+		{[]byte{0, 0}, "  "},
+		{nil, ""},
+	}
+	font := NewSevenSegFont()
+	for _, test := range tests {
+		if got := font.DecodeDigits(test.digits); got != test.want {
+			t.Errorf("% X decoded as %q, expected %q", test.digits, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"context"
+	"time"
+)
+
+// Keeps a long-running display alive. If the board is power-cycled, the
+// TM1638 comes back with random RAM and the display off. The supervisor
+// puts the shadow frame and display settings back (see TM1638.Restore).
+//
+// A chip that has browned out still answers key scans, so by default the
+// supervisor restores on every tick. With Check set, it also watches for
+// faults: while Check fails it waits, and it restores as soon as the check
+// passes again.
+//
+//	s := pkg.NewSupervisor(&p.TM1638, 5*time.Second)
+//	s.Check = p.HealthCheck
+//	go s.Run(ctx)
+type Supervisor struct {
+	Chip     *TM1638
+	Interval time.Duration // Time between ticks. DefaultSupervisorInterval if not positive.
+	Periodic bool          // Restore on every tick even if nothing looks wrong
+
+	Check     func() error // Optional health check, e.g. HealthCheck
+	OnFault   func(error)  // Optional. Called when Check fails.
+	OnRestore func(error)  // Optional. Called after each restore attempt with its result.
+}
+
+// The time between ticks for a Supervisor with no Interval.
+const DefaultSupervisorInterval = 5 * time.Second
+
+// Create a supervisor that restores the chip every interval.
+func NewSupervisor(chip *TM1638, interval time.Duration) *Supervisor {
+	return &Supervisor{Chip: chip, Interval: interval, Periodic: true}
+}
+
+// Supervise until the context is done. Returns the context's error.
+func (s *Supervisor) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSupervisorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	faulted := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if s.Check != nil {
+			err := s.Check()
+			if err != nil {
+				faulted = true
+				if s.OnFault != nil {
+					s.OnFault(err)
+				}
+				continue // Nothing to restore to yet
+			}
+		}
+
+		if faulted || s.Periodic {
+			err := s.Chip.RestoreContext(ctx)
+			if err == nil {
+				faulted = false
+			}
+			if s.OnRestore != nil {
+				s.OnRestore(err)
+			}
+		}
+	}
+}
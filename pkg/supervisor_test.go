@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// A zero Interval uses the default instead of panicking in NewTicker.
+func TestSupervisorZeroInterval(t *testing.T) {
+	s := &Supervisor{Chip: &TM1638{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestSupervisorRestores(t *testing.T) {
+	sim := NewTM1638Sim()
+	p := NewLED8KEY(sim.Pins())
+	p.Timing = TM1638Timing{}
+	if err := p.ConfigureDisplay(true, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.WriteString("42"); err != nil {
+		t.Fatal(err)
+	}
+	want := sim.Display()
+	sim.PowerCycle()
+
+	restored := make(chan error, 1)
+	s := NewSupervisor(&p.TM1638, time.Millisecond)
+	s.OnRestore = func(err error) {
+		select {
+		case restored <- err:
+		default:
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	select {
+	case err := <-restored:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no restore")
+	}
+	cancel()
+	if err := sim.Expect(want, true, 5); err != nil {
+		t.Error(err)
+	}
+}
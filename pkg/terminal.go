@@ -0,0 +1,318 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+/*
+	Draws a VirtualBoard in a terminal and presses its buttons from the
+	keyboard.
+
+	Each digit is three characters of ASCII art plus the decimal point:
+	   _
+	  |_|
+	  |_|.
+
+	Lit segments are red. The brighter the pulse width, the brighter the
+	red. Unlit segments are dark gray (or blank without colour).
+
+	A terminal only tells us when a key goes down, so a key press holds its
+	button for HoldTime. Holding a key down keeps it pressed through the
+	keyboard's auto-repeat.
+*/
+
+// Default keyboard keys for the buttons, in button order.
+const (
+	TerminalKeysLED8KEY   = "12345678"
+	TerminalKeysDISP16KEY = "1234qwerasdfzxcv"
+)
+
+// The 256-colour red for each pulse width
+var terminalBrightness = [8]int{52, 88, 88, 124, 124, 160, 196, 196}
+
+type Terminal struct {
+	Board    *VirtualBoard
+	In       io.Reader
+	Out      io.Writer
+	Keys     string        // Keyboard key for each button
+	HoldTime time.Duration // How long a key press holds its button
+	Color    bool          // Use ANSI colours
+
+	lock   sync.Mutex
+	timers map[int]*time.Timer
+}
+
+// Create a terminal front end for the board. Use os.Stdin and os.Stdout for
+// a real terminal.
+func NewTerminal(board *VirtualBoard, in io.Reader, out io.Writer) *Terminal {
+	keys := TerminalKeysLED8KEY
+	if board.Layout == LayoutDISP16KEY {
+		keys = TerminalKeysDISP16KEY
+	}
+	return &Terminal{
+		Board:    board,
+		In:       in,
+		Out:      out,
+		Keys:     keys,
+		HoldTime: 150 * time.Millisecond,
+		Color:    true,
+		timers:   map[int]*time.Timer{},
+	}
+}
+
+// Draw the board now.
+func (t *Terminal) Draw() error {
+	state := t.Board.State()
+	text := RenderASCII(state, t.Keys, t.Color)
+	// What the font makes of the digits
+	digits := state.Digits()
+	text += "\n " + strconv.Quote(t.Board.DecodeDigits(digits[:])) + "\n"
+	// Home the cursor, draw, and clear anything left below
+	text = "\x1b[H" + strings.Replace(text, "\n", "\x1b[K\r\n", -1) + "\x1b[J"
+	_, err := io.WriteString(t.Out, text)
+	return err
+}
+
+// Read keys and keep the drawing up to date until the context is done, the
+// input ends, or Ctrl-C or Esc is pressed. If In is a terminal it is put in
+// raw mode for the duration.
+//
+// Keys are read in the background. If In has a working SetReadDeadline
+// (like a net.Conn), Run interrupts the read and waits for the
+// reader before returning. Otherwise the reader ends with the next read
+// that returns, without passing on what it read.
+func (t *Terminal) Run(ctx context.Context) error {
+	if f, ok := t.In.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		old, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return err
+		}
+		defer term.Restore(int(f.Fd()), old)
+	}
+	io.WriteString(t.Out, "\x1b[2J\x1b[?25l") // Clear and hide the cursor
+	defer io.WriteString(t.Out, "\x1b[?25h\r\n")
+
+	keys := make(chan byte)
+	done := make(chan error, 1)
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go t.readKeys(keys, done, stop, finished)
+	defer t.stopReading(stop, finished)
+
+	ticker := time.NewTicker(30 * time.Millisecond)
+	defer ticker.Stop()
+	var last *BoardState
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case b := <-keys:
+			if b == 3 || b == 27 {
+				return nil // Ctrl-C or Esc
+			}
+			t.press(b)
+		case <-ticker.C:
+		}
+		state := t.Board.State()
+		if last == nil || *last != state {
+			err := t.Draw()
+			if err != nil {
+				return err
+			}
+			last = &state
+		}
+	}
+}
+
+// Pass keys from In to the keys channel until a read fails or stop is
+// closed. The read error goes to done. finished is closed on the way out.
+func (t *Terminal) readKeys(keys chan<- byte, done chan<- error, stop <-chan struct{}, finished chan<- struct{}) {
+	defer close(finished)
+	buf := make([]byte, 16)
+	for {
+		n, err := t.In.Read(buf)
+		for _, b := range buf[:n] {
+			select {
+			case keys <- b:
+			case <-stop:
+				done <- err
+				return
+			}
+		}
+		if err != nil {
+			done <- err
+			return
+		}
+		select {
+		case <-stop:
+			done <- nil
+			return
+		default:
+		}
+	}
+}
+
+// Tell the reader to stop. If the read can be interrupted, interrupt it
+// and wait for the reader.
+func (t *Terminal) stopReading(stop chan struct{}, finished <-chan struct{}) {
+	close(stop)
+	d, ok := t.In.(interface{ SetReadDeadline(time.Time) error })
+	if !ok || d.SetReadDeadline(time.Now()) != nil {
+		return // The reader goes at its next read
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+	}
+	d.SetReadDeadline(time.Time{})
+}
+
+// Press the button for a key and release it after HoldTime.
+func (t *Terminal) press(key byte) {
+	button := strings.IndexByte(t.Keys, key)
+	if button < 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.Board.SetButton(button, true)
+	if timer, ok := t.timers[button]; ok {
+		timer.Reset(t.HoldTime)
+		return
+	}
+	t.timers[button] = time.AfterFunc(t.HoldTime, func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.Board.SetButton(button, false)
+		delete(t.timers, button)
+	})
+}
+
+// Draw the board state as ASCII art. keys labels the buttons (one
+// character each). Lines end with "\n".
+func RenderASCII(state BoardState, keys string, color bool) string {
+	on := ""
+	off := ""
+	reset := ""
+	if color {
+		on = "\x1b[1;38;5;" + strconv.Itoa(terminalBrightness[state.PulseWidth&7]) + "m"
+		off = "\x1b[22;38;5;238m"
+		reset = "\x1b[0m"
+	}
+
+	digits := state.Digits()
+	if !state.Enabled {
+		digits = [8]byte{}
+	}
+
+	// One string per row of the art
+	rows := [3]string{}
+	for _, d := range digits {
+		cells := [3][4]byte{
+			{' ', 'a', ' ', ' '},
+			{'f', 'g', 'b', ' '},
+			{'e', 'd', 'c', 'x'},
+		}
+		for r := range cells {
+			for _, seg := range cells[r] {
+				rows[r] += terminalSegment(d, seg, on, off, color)
+			}
+			rows[r] += " "
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		b.WriteString(" " + row + reset + "\n")
+	}
+	b.WriteString("\n")
+
+	if state.Layout.HasLEDs() {
+		leds := state.LEDs()
+		b.WriteString(" ")
+		for _, led := range leds {
+			if led && state.Enabled {
+				b.WriteString(on + " (*)" + reset + " ")
+			} else {
+				b.WriteString(off + " ( )" + reset + " ")
+			}
+		}
+		b.WriteString("\n\n")
+	}
+
+	// The buttons: one row for the LED8KEY, a 4x4 grid for the DISP16KEY
+	perRow := 8
+	if state.Layout == LayoutDISP16KEY {
+		perRow = 4
+	}
+	for i := 0; i < state.Layout.NumButtons(); i++ {
+		if i%perRow == 0 {
+			b.WriteString(" ")
+		}
+		label := "?"
+		if i < len(keys) {
+			label = keys[i : i+1]
+		}
+		if state.Buttons[i] {
+			if color {
+				b.WriteString(" \x1b[7m[" + label + "]\x1b[0m ")
+			} else {
+				b.WriteString(" <" + label + "> ")
+			}
+		} else {
+			b.WriteString(" [" + label + "] ")
+		}
+		if i%perRow == perRow-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// The character for one cell of a digit's art.
+func terminalSegment(digit byte, seg byte, on string, off string, color bool) string {
+	var mask byte
+	var glyph string
+	switch seg {
+	case 'a':
+		mask, glyph = SegA, "_"
+	case 'b':
+		mask, glyph = SegB, "|"
+	case 'c':
+		mask, glyph = SegC, "|"
+	case 'd':
+		mask, glyph = SegD, "_"
+	case 'e':
+		mask, glyph = SegE, "|"
+	case 'f':
+		mask, glyph = SegF, "|"
+	case 'g':
+		mask, glyph = SegG, "_"
+	case 'x':
+		mask, glyph = SegDP, "."
+	default:
+		return " "
+	}
+	if digit&mask != 0 {
+		return on + glyph
+	}
+	if color {
+		return off + glyph
+	}
+	return " "
+}
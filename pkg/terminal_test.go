@@ -0,0 +1,53 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTerminalPressesButtons(t *testing.T) {
+	in, keys := io.Pipe()
+	v := NewVirtualLED8KEY()
+	term := NewTerminal(v.VirtualBoard, in, io.Discard)
+	term.HoldTime = time.Hour
+
+	result := make(chan error, 1)
+	go func() { result <- term.Run(context.Background()) }()
+	keys.Write([]byte("3"))
+	keys.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if !v.State().Buttons[2] {
+		t.Error("key 3 did not press button 3")
+	}
+}
+
+// Run stops its reader when it returns, so nothing else loses input to it.
+func TestTerminalStopsReading(t *testing.T) {
+	r, w := net.Pipe()
+	defer r.Close()
+	defer w.Close()
+
+	term := NewTerminal(NewVirtualLED8KEY().VirtualBoard, r, io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- term.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond) // Let the reader block
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	go w.Write([]byte("x"))
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1)
+	if n, err := r.Read(buf); n != 1 || buf[0] != 'x' {
+		t.Errorf("read %q, %v after Run returned", buf[:n], err)
+	}
+}
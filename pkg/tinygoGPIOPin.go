@@ -1,26 +1,64 @@
+//go:build tinygo
+
 package pkg
 
+import (
+	"machine"
+	"sync"
+)
+
 type TinyGoGPIOPin struct {
-	TGpin int
+	TGpin machine.Pin
+}
+
+// The input mode of every pin that has had SetBias. The pin is passed
+// around by value, so the bias is kept here where all copies see it.
+var tinygoInputModes = struct {
+	lock  sync.Mutex
+	modes map[machine.Pin]machine.PinMode
+}{modes: map[machine.Pin]machine.PinMode{}}
+
+func (x TinyGoGPIOPin) Write(state bool) error {
+	if state {
+		x.TGpin.High()
+	} else {
+		x.TGpin.Low()
+	}
+	return nil
+}
+
+func (x TinyGoGPIOPin) Read() (bool, error) {
+	return x.TGpin.Get(), nil
+}
+
+func (x TinyGoGPIOPin) Input() error {
+	tinygoInputModes.lock.Lock()
+	mode, exist := tinygoInputModes.modes[x.TGpin]
+	tinygoInputModes.lock.Unlock()
+	if !exist {
+		mode = machine.PinInput
+	}
+	x.TGpin.Configure(machine.PinConfig{Mode: mode})
+	return nil
 }
 
-// func (x TinyGoGPIOPin) Write(state bool) {
-// 	// if state {
-// 	// 	x.TGpin.High()
-// 	// } else {
-// 	// 	x.TGpin.Low()
-// 	// }
-// }
-
-// func (x TinyGoGPIOPin) Read() bool {
-// 	//return x.TGpin.Get()
-// 	return false
-// }
-
-// func (x TinyGoGPIOPin) Input() {
-// 	//x.TGpin.Configure(machine.PinConfig{Mode: machine.PinInput})
-// }
-
-// func (x TinyGoGPIOPin) Output() {
-// 	//x.TGpin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-// }
+func (x TinyGoGPIOPin) Output() error {
+	x.TGpin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	return nil
+}
+
+// Use the internal pull-up or pull-down (like the RP2040's) whenever the
+// pin is an input. It takes effect on the next Input.
+func (x TinyGoGPIOPin) SetBias(bias GPIOBias) error {
+	mode := machine.PinInput
+	switch bias {
+	case BiasPullUp:
+		mode = machine.PinInputPullup
+	case BiasPullDown:
+		mode = machine.PinInputPulldown
+	}
+	tinygoInputModes.lock.Lock()
+	defer tinygoInputModes.lock.Unlock()
+	tinygoInputModes.modes[x.TGpin] = mode
+	return nil
+}
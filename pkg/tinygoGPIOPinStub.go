@@ -0,0 +1,36 @@
+//go:build !tinygo
+
+package pkg
+
+import "fmt"
+
+// The "machine" package only exists under TinyGo. This stub keeps the type
+// name available in regular Go builds. Every method returns an error -- use
+// RPiGPIOPin (or another backend) when running on the Pi.
+type TinyGoGPIOPin struct {
+	TGpin uint8 // machine.Pin is a uint8 under TinyGo
+}
+
+func (x TinyGoGPIOPin) unsupported() error {
+	return fmt.Errorf("TinyGoGPIOPin %d requires a TinyGo build", x.TGpin)
+}
+
+func (x TinyGoGPIOPin) Write(state bool) error {
+	return x.unsupported()
+}
+
+func (x TinyGoGPIOPin) Read() (bool, error) {
+	return false, x.unsupported()
+}
+
+func (x TinyGoGPIOPin) Input() error {
+	return x.unsupported()
+}
+
+func (x TinyGoGPIOPin) Output() error {
+	return x.unsupported()
+}
+
+func (x TinyGoGPIOPin) SetBias(bias GPIOBias) error {
+	return x.unsupported()
+}
@@ -6,7 +6,9 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -76,70 +78,271 @@ type GPIOPin interface {
 	Output()
 }
 
+// A GPIOPin whose operations can fail. The driver uses this interface so that
+// a disconnected chip or a revoked GPIO line surfaces as an error. Wrap an
+// existing GPIOPin with CheckGPIOPin.
+type CheckedGPIOPin interface {
+	Write(bool) error
+	Read() (bool, error)
+	Input() error
+	Output() error
+}
+
+// Adapt a GPIOPin (that cannot report errors) to a CheckedGPIOPin.
+func CheckGPIOPin(pin GPIOPin) CheckedGPIOPin {
+	return checkedGPIOPin{pin}
+}
+
+type checkedGPIOPin struct {
+	pin GPIOPin
+}
+
+func (x checkedGPIOPin) Write(state bool) error {
+	x.pin.Write(state)
+	return nil
+}
+
+func (x checkedGPIOPin) Read() (bool, error) {
+	return x.pin.Read(), nil
+}
+
+func (x checkedGPIOPin) Input() error {
+	x.pin.Input()
+	return nil
+}
+
+func (x checkedGPIOPin) Output() error {
+	x.pin.Output()
+	return nil
+}
+
+// Internal bias resistor setting for a pin.
+type GPIOBias int
+
+const (
+	BiasNone GPIOBias = iota
+	BiasPullUp
+	BiasPullDown
+)
+
+// Optional pin capability: the backend can switch the pin to a native
+// open-drain output. Writing true releases the line and writing false
+// drives it low. The pin can still be read while open-drain.
+type OpenDrainGPIOPin interface {
+	OpenDrain() error
+}
+
+// Optional pin capability: the backend can apply an internal pull-up or
+// pull-down when the pin is an input.
+type BiasedGPIOPin interface {
+	SetBias(bias GPIOBias) error
+}
+
+// Bit order for reading key scanning data.
+type BitOrder int
+
+const (
+	LSBFirst BitOrder = iota // The datasheet order. The first bit on the wire is B0.
+	MSBFirst                 // Bit-reversed. The first bit on the wire is B7.
+)
+
 type TM1638 struct {
-	STROBE GPIOPin
-	CLK    GPIOPin
-	DIO    GPIOPin
+	STROBE       CheckedGPIOPin
+	CLK          CheckedGPIOPin
+	DIO          CheckedGPIOPin
+	Timing       TM1638Timing  // Defaults to TimingPi
+	ReadBitOrder BitOrder      // Defaults to LSBFirst. The boards expect LSBFirst.
+	openDrain    bool          // DIO is a native open-drain output
+	dataCommand  byte          // The last data command sent (0 if unknown)
+	shadow       [16]byte      // Everything written to the display RAM
+	control      byte          // The last display control command sent (0 if none)
+	Tracer       Tracer        // Optional. Gets every transaction.
+	trace        *traceState   // The transaction being traced
+	bus          chan struct{} // Holds a token for every bus transaction
+	busOnce      sync.Once     // Makes the bus on first use
 }
 
 // Create a new LED8Key driver with the given GPIO pins.
-func NewTM1638(pinSTROBE GPIOPin, pinCLK GPIOPin, pinDIO GPIOPin) *TM1638 {
+// Errors from setting up the lines are not reported here. A dead line will
+// fail the first transaction instead. Use NewTM1638E to see them.
+//
+// A TM1638 is safe for concurrent use. Every command holds the bus for its
+// whole transaction. Use Transaction to group several commands.
+func NewTM1638(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) *TM1638 {
 	ret := &TM1638{}
-	ret.STROBE = pinSTROBE
-	ret.CLK = pinCLK
-	ret.DIO = pinDIO
+	ret.setup(pinSTROBE, pinCLK, pinDIO)
+	return ret
+}
 
-	ret.STROBE.Write(true) // Active low -- start it high
-	ret.CLK.Write(true)    // Active low -- start it high
-	ret.DIO.Write(false)   // We'll simulate open-drain
+// NewTM1638 that reports the first error from setting up the lines. The
+// driver is returned either way.
+func NewTM1638E(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) (*TM1638, error) {
+	ret := &TM1638{}
+	err := ret.setup(pinSTROBE, pinCLK, pinDIO)
+	return ret, err
+}
 
-	ret.STROBE.Output() // Driven
-	ret.CLK.Output()    // Driven
-	ret.DIO.Input()     // We'll simulate open-drain
-	return ret
+// Wire up the pins and put the lines in their idle states. The boards
+// embed a TM1638 and call this from their constructors. Every step is
+// tried, and the first error is returned.
+func (x *TM1638) setup(pinSTROBE CheckedGPIOPin, pinCLK CheckedGPIOPin, pinDIO CheckedGPIOPin) error {
+	x.STROBE = pinSTROBE
+	x.CLK = pinCLK
+	x.DIO = pinDIO
+	x.Timing = TimingPi
+
+	var first error
+	check := func(err error) {
+		if first == nil && err != nil {
+			first = err
+		}
+	}
+
+	check(x.STROBE.Write(true)) // Active low -- start it high
+	check(x.CLK.Write(true))    // Active low -- start it high
+
+	check(x.STROBE.Output()) // Driven
+	check(x.CLK.Output())    // Driven
+
+	// Use the internal pull-up on DIO if the backend has one. The board
+	// has its own, but this helps with long wires.
+	if b, ok := x.DIO.(BiasedGPIOPin); ok {
+		check(b.SetBias(BiasPullUp))
+	}
+
+	// Prefer a native open-drain DIO. Fall back to simulating it.
+	if od, ok := x.DIO.(OpenDrainGPIOPin); ok && od.OpenDrain() == nil {
+		x.openDrain = true
+		check(x.DIO.Write(true)) // Released
+	} else {
+		check(x.DIO.Write(false)) // We'll simulate open-drain
+		check(x.DIO.Input())
+	}
+	return first
+}
+
+// Let go of DIO. The pull-up takes it to "1" unless the chip drives it.
+func (x *TM1638) releaseDIO() error {
+	if x.openDrain {
+		return x.DIO.Write(true)
+	}
+	return x.DIO.Input()
+}
+
+// Drive DIO to "0".
+func (x *TM1638) driveDIOLow() error {
+	if x.openDrain {
+		return x.DIO.Write(false)
+	}
+	return x.DIO.Output()
+}
+
+// Take the strobe low to start a transaction.
+func (x *TM1638) startTransaction(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	if x.Tracer != nil {
+		x.trace = &traceState{start: time.Now()}
+	}
+	err = x.STROBE.Write(false)
+	x.Timing.delay(x.Timing.StrobeSetup)
+	return err
+}
+
+// Take the strobe high to end a transaction. If the transaction failed
+// we still try to release the strobe, but we report the original error.
+func (x *TM1638) endTransaction(err error) error {
+	x.Timing.delay(x.Timing.StrobeHold)
+	serr := x.STROBE.Write(true)
+	x.Timing.delay(x.Timing.StrobeHold)
+	if err == nil {
+		err = serr
+	}
+	if x.trace != nil {
+		x.Tracer.Trace(newTraceRecord(x.trace.start, x.trace.sent, x.trace.read, err))
+		x.trace = nil
+	}
+	return err
 }
 
 // Twiddle the CLK and DIO lines to send one byte of data.
 // Data is sent low-bit first. The chip latches data on the
-// falling edge of the clock.
-func (x *TM1638) sendByte(value byte) {
-	// We use the data-direction here to send the data. We are simulating
-	// an open-drain output. The board must have a pullup resistor on DIO.
-	// The open-drain prevents both boards from driving the line with
-	// opposite values.
-	for i := 0; i < 8; i++ {
+// rising edge of the clock.
+func (x *TM1638) sendByte(value byte) error {
+	if x.trace != nil {
+		x.trace.sent = append(x.trace.sent, value)
+	}
+	// DIO is open-drain: natively if the backend supports it, otherwise
+	// simulated with the data-direction. The board must have a pullup
+	// resistor on DIO. The open-drain prevents both boards from driving the
+	// line with opposite values.
+	var err error
+	for i := 0; i < 8 && err == nil; i++ {
 		if (value & 1) == 1 {
-			x.DIO.Input() // Release the line, which is pulled up to "1"
+			err = x.releaseDIO() // Release the line, which is pulled up to "1"
 		} else {
-			x.DIO.Output() // Drive the line to "0"
+			err = x.driveDIOLow() // Drive the line to "0"
+		}
+		if err == nil {
+			err = x.CLK.Write(false) // Clock low. DIO is already set up.
+		}
+		if err == nil {
+			value = value >> 1 // Next bit
+			x.Timing.delay(x.Timing.ClockHalfPeriod)
+			err = x.CLK.Write(true) // The chip latches the bit on this rising edge
+		}
+		if err == nil {
+			x.Timing.delay(x.Timing.ClockHalfPeriod)
 		}
-		x.CLK.Write(false) // Data is read on high to low transition
-		time.Sleep(time.Microsecond)
-		value = value >> 1 // Next bit
-		time.Sleep(time.Microsecond)
-		x.CLK.Write(true) // Get ready for next cycle
-		time.Sleep(time.Microsecond)
 	}
-	x.DIO.Input()
+	// Let go of DIO even if a bit failed, so it isn't left driven low.
+	// The first error wins.
+	rerr := x.releaseDIO()
+	if err == nil {
+		err = rerr
+	}
+	return err
 }
 
 // Twiddle the CLK and DIO lines to read one byte of data.
-// Data is sent low-bit first. Take the clock low to extract the bit.
-// Read the bit just before taking the clock high again.
-func (x *TM1638) readByte() byte {
-	var ret byte = 0 // Accumulate value here
-	x.DIO.Input()    // We are reading
+// Data is sent low-bit first (see ReadBitOrder). Take the clock low to
+// extract the bit. Read the bit just before taking the clock high again.
+func (x *TM1638) readByte() (byte, error) {
+	var ret byte = 0      // Accumulate value here
+	err := x.releaseDIO() // We are reading
+	if err != nil {
+		return 0, err
+	}
 	for i := 0; i < 8; i++ {
-		x.CLK.Write(false) // Tell the chip to write its data
-		ret = ret << 1     // Move our bits over for the new one
-		time.Sleep(time.Millisecond)
-		if x.DIO.Read() == true {
-			ret |= 1 // Add in a 1 if the data is 1
+		err = x.CLK.Write(false) // Tell the chip to write its data
+		if err != nil {
+			return 0, err
+		}
+		x.Timing.delay(x.Timing.ReadSettle)
+		bit, err := x.DIO.Read()
+		if err != nil {
+			return 0, err
+		}
+		if bit {
+			// Add in a 1 if the data is 1
+			if x.ReadBitOrder == MSBFirst {
+				ret |= 0x80 >> uint(i)
+			} else {
+				ret |= 1 << uint(i)
+			}
+		}
+		err = x.CLK.Write(true) // Ready for next cycle
+		if err != nil {
+			return 0, err
 		}
-		x.CLK.Write(true) // Ready for next cycle
-		time.Sleep(time.Microsecond)
+		x.Timing.delay(x.Timing.ClockHalfPeriod)
 	}
-	return ret
+	if x.trace != nil {
+		x.trace.read = append(x.trace.read, ret)
+	}
+	return ret, nil
 }
 
 // Configure the brightness of all outputs.
@@ -155,6 +358,22 @@ func (x *TM1638) readByte() byte {
 //   - 6 = 13/16
 //   - 7 = 14/16 (bright)
 func (x *TM1638) ConfigureDisplay(enabled bool, pulseWidth int) error {
+	return x.ConfigureDisplayContext(context.Background(), enabled, pulseWidth)
+}
+
+// ConfigureDisplay that gives up if the context is done before the bus
+// is free.
+func (x *TM1638) ConfigureDisplayContext(ctx context.Context, enabled bool, pulseWidth int) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return x.configureDisplay(ctx, enabled, pulseWidth)
+}
+
+// ConfigureDisplay with the bus already locked.
+func (x *TM1638) configureDisplay(ctx context.Context, enabled bool, pulseWidth int) error {
 	// 1. Active strobe
 	// 2. Send command
 	// 3. Release strobe
@@ -171,18 +390,36 @@ func (x *TM1638) ConfigureDisplay(enabled bool, pulseWidth int) error {
 	}
 	cmd |= byte(pulseWidth)
 
-	x.STROBE.Write(false)
-	time.Sleep(time.Microsecond)
-	x.sendByte(cmd)
-	x.STROBE.Write(true)
-	time.Sleep(time.Microsecond)
-
-	return nil
+	err := x.startTransaction(ctx)
+	if err == nil {
+		err = x.sendByte(cmd)
+	}
+	err = x.endTransaction(err)
+	if err == nil {
+		x.control = cmd
+	}
+	return err
 }
 
 // Read up to four bytes of key scanning data.
 // Four is all there are.
 func (x *TM1638) ReadScanningData(data []byte) error {
+	return x.ReadScanningDataContext(context.Background(), data)
+}
+
+// ReadScanningData that can be cancelled. The context is checked while
+// waiting for the bus and between bytes.
+func (x *TM1638) ReadScanningDataContext(ctx context.Context, data []byte) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return x.readScanningData(ctx, data)
+}
+
+// ReadScanningData with the bus already locked.
+func (x *TM1638) readScanningData(ctx context.Context, data []byte) error {
 	// 1. Active strobe
 	// 2. Send read command
 	// 3. Read data bytes
@@ -190,46 +427,111 @@ func (x *TM1638) ReadScanningData(data []byte) error {
 	if (len(data) < 1) || (len(data) > 4) {
 		return fmt.Errorf("Can only read 1 to 4 bytes")
 	}
-	x.STROBE.Write(false)
-	time.Sleep(time.Microsecond)
-	x.sendByte(0b01_00_0_010) // Read command
-	time.Sleep(time.Microsecond)
-	for i := int(0); i < len(data); i++ {
-		v := x.readByte()
-		data[i] = v
-		time.Sleep(time.Microsecond)
-	}
-	x.STROBE.Write(true)
-	time.Sleep(time.Microsecond)
-	return nil
+	x.dataCommand = 0 // Unknown until it is sent
+	err := x.startTransaction(ctx)
+	if err == nil {
+		err = x.sendByte(dataCommandRead)
+		x.Timing.delay(x.Timing.ReadWait)
+	}
+	if err == nil {
+		x.dataCommand = dataCommandRead
+	}
+	for i := int(0); i < len(data) && err == nil; i++ {
+		err = ctx.Err()
+		if err == nil {
+			data[i], err = x.readByte()
+		}
+	}
+	return x.endTransaction(err)
 }
 
 // Prepare the chip to take data.
 //   - autoIncrement = true to bump the address automatically after every write
 func (x *TM1638) InitWriteData(autoIncrement bool) error {
+	return x.InitWriteDataContext(context.Background(), autoIncrement)
+}
+
+// InitWriteData that gives up if the context is done before the bus is free.
+func (x *TM1638) InitWriteDataContext(ctx context.Context, autoIncrement bool) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return x.initWriteData(ctx, autoIncrement)
+}
+
+// InitWriteData with the bus already locked.
+func (x *TM1638) initWriteData(ctx context.Context, autoIncrement bool) error {
+	if autoIncrement {
+		return x.sendDataCommand(ctx, dataCommandWrite)
+	}
+	return x.sendDataCommand(ctx, dataCommandWriteFixed)
+}
+
+// Data commands (01_00_I_tMM)
+const (
+	dataCommandWrite      = 0b01_00_0_000 // Write with auto-increment
+	dataCommandWriteFixed = 0b01_00_1_000 // Write to a fixed address
+	dataCommandRead       = 0b01_00_0_010 // Read key scanning data
+)
+
+// Send a data command in its own transaction and remember it.
+func (x *TM1638) sendDataCommand(ctx context.Context, cmd byte) error {
 	// 1. Active strobe
 	// 2. Send the command
 	// 3. Release the strobe
 
-	x.STROBE.Write(false)
-	time.Sleep(time.Microsecond)
-	//                     I
-	var cmd byte = 0b01_00_0_000
-	if !autoIncrement {
-		cmd |= 0b1_000
+	x.dataCommand = 0 // Unknown until it is sent
+	err := x.startTransaction(ctx)
+	if err == nil {
+		err = x.sendByte(cmd)
+	}
+	err = x.endTransaction(err)
+	if err == nil {
+		x.dataCommand = cmd
+	}
+	return err
+}
+
+// Send the data command unless the chip is already in that mode.
+func (x *TM1638) ensureDataCommand(ctx context.Context, cmd byte) error {
+	if x.dataCommand == cmd {
+		return nil
 	}
-	x.sendByte(cmd)
-	time.Sleep(time.Microsecond)
-	x.STROBE.Write(true)
-	time.Sleep(time.Microsecond)
+	return x.sendDataCommand(ctx, cmd)
+}
 
-	return nil
+// True if the chip is in either write mode. One byte after an address goes
+// to that address in both.
+func (x *TM1638) inWriteMode() bool {
+	return x.dataCommand == dataCommandWrite || x.dataCommand == dataCommandWriteFixed
 }
 
-// Send an address followed by stream of bytes.
+// Send an address followed by stream of bytes. This puts the chip in
+// auto-increment mode first if it isn't already. A single byte goes in
+// with either write mode, so it needs no data command if the chip is
+// already taking data.
 //   - address = the starting address (0x00 to 0x0F)
 //   - data = slice of bytes
 func (x *TM1638) WriteData(address int, data []byte) error {
+	return x.WriteDataContext(context.Background(), address, data)
+}
+
+// WriteData that can be cancelled. The context is checked while waiting
+// for the bus and between bytes. A cancelled write releases the strobe
+// early: the bytes already sent stay in the chip.
+func (x *TM1638) WriteDataContext(ctx context.Context, address int, data []byte) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return x.writeData(ctx, address, data)
+}
+
+// WriteData with the bus already locked.
+func (x *TM1638) writeData(ctx context.Context, address int, data []byte) error {
 	// 1. Active strobe
 	// 2. Send Address
 	// 3. Send each byte of data
@@ -241,15 +543,223 @@ func (x *TM1638) WriteData(address int, data []byte) error {
 	if len(data) < 1 || len(data) > 16 {
 		return fmt.Errorf("Data must be 1 to 16 bytes")
 	}
-	x.STROBE.Write(false)
-	time.Sleep(time.Microsecond)
-	address |= 0b11_00_0000
-	x.sendByte(byte(address))
-	time.Sleep(time.Microsecond)
-	for _, v := range data {
-		x.sendByte(v)
+	if len(data) > 1 || !x.inWriteMode() {
+		err := x.ensureDataCommand(ctx, dataCommandWrite)
+		if err != nil {
+			return err
+		}
+	}
+	return x.writeRun(ctx, address, data)
+}
+
+// Send one address command and the bytes for it and the addresses after
+// it in a single strobe. The chip must already be in a mode that takes
+// them.
+func (x *TM1638) writeRun(ctx context.Context, address int, data []byte) error {
+	err := x.startTransaction(ctx)
+	if err == nil {
+		err = x.sendByte(byte(address | 0b11_00_0000))
+	}
+	for i := 0; i < len(data) && err == nil; i++ {
+		err = ctx.Err()
+		if err == nil {
+			err = x.sendByte(data[i])
+		}
+		if err == nil {
+			x.shadow[(address+i)&0x0F] = data[i] // The chip wraps around
+		}
+	}
+	return x.endTransaction(err)
+}
+
+// One byte of display RAM.
+type AddressByte struct {
+	Address int  // 0x00 to 0x0F
+	Data    byte // The value for that address
+}
+
+// Update scattered addresses, holding the bus for all of the writes. The
+// chip takes one address command per strobe, so only addresses that follow
+// each other in writes (like 4, 5, 6) can share a strobe: each such run is
+// sent in auto-increment mode. Addresses that stand alone go in one strobe
+// each, in whichever write mode the chip is already in (fixed-address if
+// it isn't taking data). So there is at most one data command up front.
+func (x *TM1638) WriteScattered(writes []AddressByte) error {
+	return x.WriteScatteredContext(context.Background(), writes)
+}
+
+// WriteScattered that can be cancelled between addresses.
+func (x *TM1638) WriteScatteredContext(ctx context.Context, writes []AddressByte) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return x.writeScattered(ctx, writes)
+}
+
+// WriteScattered with the bus already locked.
+func (x *TM1638) writeScattered(ctx context.Context, writes []AddressByte) error {
+	// 1. Send the data command (if needed)
+	// For each run of consecutive addresses:
+	//   2. Active strobe
+	//   3. Send the first address
+	//   4. Send the bytes of data
+	//   5. Release strobe
+
+	for _, w := range writes {
+		if w.Address < 0 || w.Address > 0x0F {
+			return fmt.Errorf("Invalid address %d. Must be 0 to 15.", w.Address)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	runs := [][]AddressByte{}
+	for i, w := range writes {
+		if i > 0 && w.Address == writes[i-1].Address+1 {
+			runs[len(runs)-1] = append(runs[len(runs)-1], w)
+		} else {
+			runs = append(runs, []AddressByte{w})
+		}
+	}
+
+	var err error
+	if len(runs) < len(writes) {
+		err = x.ensureDataCommand(ctx, dataCommandWrite)
+	} else if !x.inWriteMode() {
+		err = x.ensureDataCommand(ctx, dataCommandWriteFixed)
+	}
+	for i := 0; i < len(runs) && err == nil; i++ {
+		data := make([]byte, len(runs[i]))
+		for j, w := range runs[i] {
+			data[j] = w.Data
+		}
+		err = x.writeRun(ctx, runs[i][0].Address, data)
+	}
+	return err
+}
+
+// A copy of everything written to the display RAM. The chip can't be read
+// back, so this is what it should be showing.
+func (x *TM1638) Frame() [16]byte {
+	x.lock()
+	defer x.release()
+	return x.shadow
+}
+
+// The last display settings sent. known is false until ConfigureDisplay
+// has succeeded once.
+func (x *TM1638) DisplayControl() (enabled bool, pulseWidth int, known bool) {
+	x.lock()
+	defer x.release()
+	return x.control&0b1_000 != 0, int(x.control & 0b111), x.control != 0
+}
+
+// Send the whole shadow frame and the last display settings again. Use
+// this after the chip loses power and comes back with random RAM and the
+// display off.
+func (x *TM1638) Restore() error {
+	return x.RestoreContext(context.Background())
+}
+
+// Restore that can be cancelled.
+func (x *TM1638) RestoreContext(ctx context.Context) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+
+	x.dataCommand = 0 // A fresh chip needs the data command again
+	frame := x.shadow
+	err = x.writeData(ctx, 0, frame[:])
+	if err != nil {
+		return err
+	}
+	if x.control != 0 {
+		return x.configureDisplay(ctx, x.control&0b1_000 != 0, int(x.control&0b111))
 	}
-	x.STROBE.Write(true)
-	time.Sleep(time.Microsecond)
 	return nil
 }
+
+// Wait for the bus to be free. The bus is made on first use, so a TM1638
+// that wasn't built by NewTM1638 works too. A context that is already done
+// never gets the bus, even if it is free.
+func (x *TM1638) acquire(ctx context.Context) error {
+	x.busOnce.Do(func() { x.bus = make(chan struct{}, 1) })
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	select {
+	case x.bus <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait for the bus with no way to give up. This can't fail: acquire only
+// returns an error when the context is done, and Background never is.
+func (x *TM1638) lock() {
+	x.acquire(context.Background())
+}
+
+// Let the next transaction have the bus.
+func (x *TM1638) release() {
+	<-x.bus
+}
+
+// The bus operations available inside a Transaction.
+type TM1638Bus interface {
+	ConfigureDisplay(enabled bool, pulseWidth int) error
+	ReadScanningData(data []byte) error
+	InitWriteData(autoIncrement bool) error
+	WriteData(address int, data []byte) error
+	WriteScattered(writes []AddressByte) error
+}
+
+// Run several commands as one atomic group. No other goroutine can use the
+// chip until fn returns. Inside fn, use the given bus -- calling the TM1638
+// directly would deadlock.
+func (x *TM1638) Transaction(fn func(bus TM1638Bus) error) error {
+	return x.TransactionContext(context.Background(), fn)
+}
+
+// Transaction that can be cancelled. Every command on the bus checks the
+// context.
+func (x *TM1638) TransactionContext(ctx context.Context, fn func(bus TM1638Bus) error) error {
+	err := x.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer x.release()
+	return fn(lockedTM1638{x, ctx})
+}
+
+// The TM1638 with the lock already held.
+type lockedTM1638 struct {
+	x   *TM1638
+	ctx context.Context
+}
+
+func (b lockedTM1638) ConfigureDisplay(enabled bool, pulseWidth int) error {
+	return b.x.configureDisplay(b.ctx, enabled, pulseWidth)
+}
+
+func (b lockedTM1638) ReadScanningData(data []byte) error {
+	return b.x.readScanningData(b.ctx, data)
+}
+
+func (b lockedTM1638) InitWriteData(autoIncrement bool) error {
+	return b.x.initWriteData(b.ctx, autoIncrement)
+}
+
+func (b lockedTM1638) WriteData(address int, data []byte) error {
+	return b.x.writeData(b.ctx, address, data)
+}
+
+func (b lockedTM1638) WriteScattered(writes []AddressByte) error {
+	return b.x.writeScattered(b.ctx, writes)
+}
@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"math/bits"
+)
+
+/*
+	The TM1638 scans a 3x8 key matrix: K1-K3 by SEG1-SEG8 (the datasheet
+	calls the SEG lines KS1-KS8 here). The four bytes of key scanning data
+	are laid out like this:
+
+	         B0     B1     B2     B3  B4     B5     B6     B7
+	  BYTE1  K3KS1  K2KS1  K1KS1  -   K3KS2  K2KS2  K1KS2  -
+	  BYTE2  K3KS3  K2KS3  K1KS3  -   K3KS4  K2KS4  K1KS4  -
+	  BYTE3  K3KS5  K2KS5  K1KS5  -   K3KS6  K2KS6  K1KS6  -
+	  BYTE4  K3KS7  K2KS7  K1KS7  -   K3KS8  K2KS8  K1KS8  -
+
+	Boards wire their buttons to different places in the matrix. Describe
+	the wiring with a slice of KeyPositions and decode with KeyMatrix.Decode.
+*/
+
+// Position of a key in the TM1638's scan matrix (datasheet numbering).
+type KeyPosition struct {
+	K   int // 1 to 3
+	SEG int // 1 to 8
+}
+
+// The byte (0-3) and bit mask in the key scanning data for this key.
+func (p KeyPosition) ScanBit() (int, byte) {
+	index := (p.SEG - 1) / 2
+	bit := uint(3 - p.K)
+	if p.SEG%2 == 0 {
+		bit += 4
+	}
+	return index, 1 << bit
+}
+
+// All 24 keys of the matrix. Bit (K-1)*8 + (SEG-1) is set for each
+// pressed key.
+type KeyMatrix uint32
+
+// The matrix with just this one key pressed.
+func KeyMatrixOf(pos KeyPosition) KeyMatrix {
+	return 1 << uint((pos.K-1)*8+(pos.SEG-1))
+}
+
+// Decode the four bytes of key scanning data (read LSBFirst).
+func DecodeKeyMatrix(data [4]byte) KeyMatrix {
+	var ret KeyMatrix
+	for k := 1; k <= 3; k++ {
+		for seg := 1; seg <= 8; seg++ {
+			pos := KeyPosition{k, seg}
+			index, mask := pos.ScanBit()
+			if data[index]&mask != 0 {
+				ret |= KeyMatrixOf(pos)
+			}
+		}
+	}
+	return ret
+}
+
+// The four bytes of key scanning data the chip reports for this matrix.
+func (m KeyMatrix) ScanData() [4]byte {
+	var ret [4]byte
+	for _, pos := range m.Keys() {
+		index, mask := pos.ScanBit()
+		ret[index] |= mask
+	}
+	return ret
+}
+
+// True if the key is pressed.
+func (m KeyMatrix) Pressed(pos KeyPosition) bool {
+	return m&KeyMatrixOf(pos) != 0
+}
+
+// The number of keys pressed.
+func (m KeyMatrix) Count() int {
+	return bits.OnesCount32(uint32(m))
+}
+
+// The pressed keys in K then SEG order.
+func (m KeyMatrix) Keys() []KeyPosition {
+	ret := []KeyPosition{}
+	for k := 1; k <= 3; k++ {
+		for seg := 1; seg <= 8; seg++ {
+			pos := KeyPosition{k, seg}
+			if m.Pressed(pos) {
+				ret = append(ret, pos)
+			}
+		}
+	}
+	return ret
+}
+
+// The one pressed key. Returns false if no keys or more than one key
+// is pressed.
+func (m KeyMatrix) Single() (KeyPosition, bool) {
+	if m.Count() != 1 {
+		return KeyPosition{}, false
+	}
+	return m.Keys()[0], true
+}
+
+// The matrix as booleans: [K-1][SEG-1].
+func (m KeyMatrix) Array() [3][8]bool {
+	var ret [3][8]bool
+	for k := 1; k <= 3; k++ {
+		for seg := 1; seg <= 8; seg++ {
+			ret[k-1][seg-1] = m.Pressed(KeyPosition{k, seg})
+		}
+	}
+	return ret
+}
+
+// Map the matrix onto a board's buttons. buttons[i] is set from keys[i].
+func (m KeyMatrix) Decode(keys []KeyPosition, buttons []bool) {
+	for i, pos := range keys {
+		if i < len(buttons) {
+			buttons[i] = m.Pressed(pos)
+		}
+	}
+}
+
+// Read the full key matrix from the chip. This expects ReadBitOrder to
+// be LSBFirst.
+func (x *TM1638) ReadKeyMatrix() (KeyMatrix, error) {
+	return x.ReadKeyMatrixContext(context.Background())
+}
+
+// ReadKeyMatrix that can be cancelled.
+func (x *TM1638) ReadKeyMatrixContext(ctx context.Context) (KeyMatrix, error) {
+	data := []byte{0, 0, 0, 0}
+	err := x.ReadScanningDataContext(ctx, data)
+	if err != nil {
+		return 0, err
+	}
+	return DecodeKeyMatrix([4]byte{data[0], data[1], data[2], data[3]}), nil
+}
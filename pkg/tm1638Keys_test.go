@@ -0,0 +1,185 @@
+package pkg
+
+import "testing"
+
+// The key scanning data for each button with only that button pressed,
+// straight from the table in the datasheet (see tm1638Keys.go).
+var led8KeyScanData = [8][4]byte{
+	{0x01, 0x00, 0x00, 0x00}, // K3/KS1 is BYTE1 B0
+	{0x00, 0x01, 0x00, 0x00}, // K3/KS3 is BYTE2 B0
+	{0x00, 0x00, 0x01, 0x00}, // K3/KS5 is BYTE3 B0
+	{0x00, 0x00, 0x00, 0x01}, // K3/KS7 is BYTE4 B0
+	{0x10, 0x00, 0x00, 0x00}, // K3/KS2 is BYTE1 B4
+	{0x00, 0x10, 0x00, 0x00}, // K3/KS4 is BYTE2 B4
+	{0x00, 0x00, 0x10, 0x00}, // K3/KS6 is BYTE3 B4
+	{0x00, 0x00, 0x00, 0x10}, // K3/KS8 is BYTE4 B4
+}
+
+var disp16KeyScanData = [16][4]byte{
+	{0x04, 0x00, 0x00, 0x00}, // K1/KS1 is BYTE1 B2
+	{0x40, 0x00, 0x00, 0x00}, // K1/KS2 is BYTE1 B6
+	{0x00, 0x04, 0x00, 0x00}, // K1/KS3 is BYTE2 B2
+	{0x00, 0x40, 0x00, 0x00}, // K1/KS4 is BYTE2 B6
+	{0x00, 0x00, 0x04, 0x00}, // K1/KS5 is BYTE3 B2
+	{0x00, 0x00, 0x40, 0x00}, // K1/KS6 is BYTE3 B6
+	{0x00, 0x00, 0x00, 0x04}, // K1/KS7 is BYTE4 B2
+	{0x00, 0x00, 0x00, 0x40}, // K1/KS8 is BYTE4 B6
+	{0x02, 0x00, 0x00, 0x00}, // K2/KS1 is BYTE1 B1
+	{0x20, 0x00, 0x00, 0x00}, // K2/KS2 is BYTE1 B5
+	{0x00, 0x02, 0x00, 0x00}, // K2/KS3 is BYTE2 B1
+	{0x00, 0x20, 0x00, 0x00}, // K2/KS4 is BYTE2 B5
+	{0x00, 0x00, 0x02, 0x00}, // K2/KS5 is BYTE3 B1
+	{0x00, 0x00, 0x20, 0x00}, // K2/KS6 is BYTE3 B5
+	{0x00, 0x00, 0x00, 0x02}, // K2/KS7 is BYTE4 B1
+	{0x00, 0x00, 0x00, 0x20}, // K2/KS8 is BYTE4 B5
+}
+
+// Every button reads back from the chip as its datasheet scan bits and
+// decodes as just that button. This runs the real driver (bit-banging and
+// all) against the simulated chip.
+func TestKeyMaps(t *testing.T) {
+	sim := NewTM1638Sim()
+
+	led8 := NewLED8KEY(sim.Pins())
+	led8.Timing = TM1638Timing{} // The simulator needs no delays
+	for i, pos := range LED8KEYKeys {
+		expectScanData(t, &led8.TM1638, sim, pos, led8KeyScanData[i])
+		var buttons [8]bool
+		if err := led8.ReadButtons(&buttons); err != nil {
+			t.Fatal(err)
+		}
+		expectOneButton(t, "LED8KEY", buttons[:], i)
+	}
+
+	disp16 := NewDISP16KEY(sim.Pins())
+	disp16.Timing = TM1638Timing{}
+	for i, pos := range DISP16KEYKeys {
+		expectScanData(t, &disp16.TM1638, sim, pos, disp16KeyScanData[i])
+		var buttons [16]bool
+		if err := disp16.ReadButtons(&buttons); err != nil {
+			t.Fatal(err)
+		}
+		expectOneButton(t, "DISP16KEY", buttons[:], i)
+	}
+}
+
+// Press just the one key and check the raw scan bytes.
+func expectScanData(t *testing.T, chip *TM1638, sim *TM1638Sim, pos KeyPosition, want [4]byte) {
+	t.Helper()
+	sim.ReleaseKeys()
+	sim.SetKey(pos, true)
+	data := []byte{0, 0, 0, 0}
+	if err := chip.ReadScanningData(data); err != nil {
+		t.Fatal(err)
+	}
+	if [4]byte{data[0], data[1], data[2], data[3]} != want {
+		t.Errorf("K%d/KS%d read as % X, expected % X", pos.K, pos.SEG, data, want[:])
+	}
+	if got := KeyMatrixOf(pos).ScanData(); got != want {
+		t.Errorf("K%d/KS%d scan data is % X, expected % X", pos.K, pos.SEG, got[:], want[:])
+	}
+}
+
+// Exactly the one button should be pressed.
+func expectOneButton(t *testing.T, board string, buttons []bool, pressed int) {
+	t.Helper()
+	for i, b := range buttons {
+		if b != (i == pressed) {
+			t.Errorf("%s button %d decoded as %v", board, pressed, buttons)
+			return
+		}
+	}
+}
+
+// Several keys at once, read from the chip and decoded. K1/KS2 and K3/KS2
+// share a SEG line (and a scan byte).
+func TestKeyMatrixMultipleKeys(t *testing.T) {
+	tests := []struct {
+		keys []KeyPosition
+		scan [4]byte
+	}{
+		{[]KeyPosition{{1, 2}, {3, 2}}, [4]byte{0x50, 0x00, 0x00, 0x00}},
+		{[]KeyPosition{{1, 2}, {2, 2}, {3, 2}}, [4]byte{0x70, 0x00, 0x00, 0x00}},
+		{[]KeyPosition{{3, 1}, {3, 2}, {2, 8}}, [4]byte{0x11, 0x00, 0x00, 0x20}},
+		{[]KeyPosition{{1, 1}, {2, 4}, {3, 5}, {1, 8}}, [4]byte{0x04, 0x20, 0x01, 0x40}},
+	}
+	sim := NewTM1638Sim()
+	chip := NewTM1638(sim.Pins())
+	chip.Timing = TM1638Timing{}
+	for _, test := range tests {
+		sim.ReleaseKeys()
+		for _, pos := range test.keys {
+			sim.SetKey(pos, true)
+		}
+		m, err := chip.ReadKeyMatrix()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.ScanData(); got != test.scan {
+			t.Errorf("%v scan data % X, expected % X", test.keys, got[:], test.scan[:])
+		}
+		if m.Count() != len(test.keys) {
+			t.Errorf("%v counted %d keys", test.keys, m.Count())
+		}
+		if _, ok := m.Single(); ok {
+			t.Errorf("%v is a single key", test.keys)
+		}
+		keys := m.Keys()
+		if len(keys) != len(test.keys) {
+			t.Errorf("%v read as %v", test.keys, keys)
+		}
+		array := m.Array()
+		pressed := 0
+		for k := range array {
+			for seg := range array[k] {
+				if array[k][seg] {
+					pressed++
+				}
+			}
+		}
+		for _, pos := range test.keys {
+			if !array[pos.K-1][pos.SEG-1] || !m.Pressed(pos) {
+				t.Errorf("%v: K%d/KS%d not pressed", test.keys, pos.K, pos.SEG)
+			}
+		}
+		if pressed != len(test.keys) {
+			t.Errorf("%v: %d keys set in the array", test.keys, pressed)
+		}
+	}
+
+	// Keys comes back in K then SEG order
+	m := KeyMatrixOf(KeyPosition{3, 1}) | KeyMatrixOf(KeyPosition{1, 7}) | KeyMatrixOf(KeyPosition{1, 2})
+	keys := m.Keys()
+	if len(keys) != 3 || keys[0] != (KeyPosition{1, 2}) || keys[1] != (KeyPosition{1, 7}) || keys[2] != (KeyPosition{3, 1}) {
+		t.Errorf("keys %v", keys)
+	}
+	if DecodeKeyMatrix(m.ScanData()) != m {
+		t.Errorf("%v didn't survive the scan data", keys)
+	}
+
+	if pos, ok := KeyMatrixOf(KeyPosition{2, 6}).Single(); !ok || pos != (KeyPosition{2, 6}) {
+		t.Errorf("single key %v, %v", pos, ok)
+	}
+	if _, ok := KeyMatrix(0).Single(); ok {
+		t.Error("no keys is a single key")
+	}
+}
+
+// Two buttons wired to the same SEG line on the DISP16KEY read together.
+func TestDISP16KEYTwoButtons(t *testing.T) {
+	sim := NewTM1638Sim()
+	p := NewDISP16KEY(sim.Pins())
+	p.Timing = TM1638Timing{}
+	sim.SetKey(DISP16KEYKeys[1], true) // K1/KS2
+	sim.SetKey(DISP16KEYKeys[9], true) // K2/KS2
+	var buttons [16]bool
+	if err := p.ReadButtons(&buttons); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range buttons {
+		if b != (i == 1 || i == 9) {
+			t.Errorf("buttons %v", buttons)
+			break
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+	There is no way to read back the display RAM, but the key scanning read
+	tells us a lot about the health of the bus:
+
+	  - With no chip (or DIO not connected) nothing ever drives DIO. The
+	    pull-up makes every scan byte 0xFF.
+	  - With DIO shorted to ground, the line reads low even while idle.
+	  - A real chip never sets B3 or B7 of a scan byte. Boards also only
+	    wire some of the 24 keys. Anything else is a miswired board or a
+	    noisy line.
+*/
+
+// What Probe found wrong.
+type FaultKind int
+
+const (
+	FaultNoChip         FaultKind = iota // Every scan byte read 0xFF
+	FaultDIOStuckLow                     // DIO reads low while nothing drives it
+	FaultImpossibleKeys                  // Scan bits that no chip or board can produce
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultNoChip:
+		return "no chip"
+	case FaultDIOStuckLow:
+		return "DIO stuck low"
+	case FaultImpossibleKeys:
+		return "impossible key pattern"
+	}
+	return "unknown fault"
+}
+
+// The error Probe and HealthCheck return for a sick board. Use errors.As to
+// tell it apart from a GPIO error.
+type BoardFault struct {
+	Kind FaultKind
+	Scan [4]byte // The key scanning data that was read (if any)
+}
+
+func (e *BoardFault) Error() string {
+	if e.Kind == FaultDIOStuckLow {
+		return fmt.Sprintf("TM1638 fault: %s", e.Kind)
+	}
+	return fmt.Sprintf("TM1638 fault: %s (scan % X)", e.Kind, e.Scan[:])
+}
+
+// Check that a chip is on the bus and the DIO line works. Returns a
+// *BoardFault for a bad board, another error for a GPIO problem, or nil.
+func (x *TM1638) Probe() error {
+	_, err := x.probe(context.Background(), 0)
+	return err
+}
+
+// Probe that can be cancelled.
+func (x *TM1638) ProbeContext(ctx context.Context) error {
+	_, err := x.probe(ctx, 0)
+	return err
+}
+
+// Run the checks and also flag any pressed key that is not in the wired
+// matrix (0 to skip that check). Returns the keys that were read.
+func (x *TM1638) probe(ctx context.Context, wired KeyMatrix) (KeyMatrix, error) {
+	err := x.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer x.release()
+
+	// Idle: nothing drives DIO. The pull-up should win.
+	err = x.releaseDIO()
+	if err != nil {
+		return 0, err
+	}
+	idle, err := x.DIO.Read()
+	if err != nil {
+		return 0, err
+	}
+	if !idle {
+		return 0, &BoardFault{Kind: FaultDIOStuckLow}
+	}
+
+	data := []byte{0, 0, 0, 0}
+	err = x.readScanningData(ctx, data)
+	if err != nil {
+		return 0, err
+	}
+	scan := [4]byte{data[0], data[1], data[2], data[3]}
+
+	if scan == [4]byte{0xFF, 0xFF, 0xFF, 0xFF} {
+		return 0, &BoardFault{Kind: FaultNoChip, Scan: scan}
+	}
+
+	var unused byte = 0b1000_1000 // B3 and B7
+	if x.ReadBitOrder == MSBFirst {
+		unused = 0b0001_0001
+	}
+	for _, b := range scan {
+		if b&unused != 0 {
+			return 0, &BoardFault{Kind: FaultImpossibleKeys, Scan: scan}
+		}
+	}
+
+	if x.ReadBitOrder == MSBFirst {
+		for i := range scan {
+			scan[i] = reverseBits(scan[i])
+		}
+	}
+	keys := DecodeKeyMatrix(scan)
+	if wired != 0 && keys&^wired != 0 {
+		return 0, &BoardFault{Kind: FaultImpossibleKeys, Scan: scan}
+	}
+	return keys, nil
+}
+
+func reverseBits(b byte) byte {
+	var ret byte
+	for i := 0; i < 8; i++ {
+		ret = ret<<1 | b&1
+		b >>= 1
+	}
+	return ret
+}
+
+// The matrix with every key in the list pressed.
+func wiredKeys(keys []KeyPosition) KeyMatrix {
+	var ret KeyMatrix
+	for _, pos := range keys {
+		ret |= KeyMatrixOf(pos)
+	}
+	return ret
+}
+
+// Probe the chip and check that only the board's 8 buttons are pressed.
+func (x *LED8KEY) HealthCheck() error {
+	return x.HealthCheckContext(context.Background())
+}
+
+// HealthCheck that can be cancelled.
+func (x *LED8KEY) HealthCheckContext(ctx context.Context) error {
+	_, err := x.probe(ctx, wiredKeys(LED8KEYKeys[:]))
+	return err
+}
+
+// Probe the chip and check that only the board's 16 buttons are pressed.
+func (x *DISP16KEY) HealthCheck() error {
+	return x.HealthCheckContext(context.Background())
+}
+
+// HealthCheck that can be cancelled.
+func (x *DISP16KEY) HealthCheckContext(ctx context.Context) error {
+	_, err := x.probe(ctx, wiredKeys(DISP16KEYKeys[:]))
+	return err
+}
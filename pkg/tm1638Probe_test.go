@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// The fault kind in err, or -1 if err is nil or not a *BoardFault.
+func faultKind(err error) FaultKind {
+	var fault *BoardFault
+	if errors.As(err, &fault) {
+		return fault.Kind
+	}
+	return -1
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*TM1638Sim)
+		fault FaultKind // -1 for healthy
+	}{
+		{"healthy", func(*TM1638Sim) {}, -1},
+		{"healthy with a key down", func(s *TM1638Sim) { s.SetKey(KeyPosition{2, 7}, true) }, -1},
+		{"disconnected", func(s *TM1638Sim) { s.SetDisconnected(true) }, FaultNoChip},
+		{"DIO stuck low", func(s *TM1638Sim) { s.SetDIOStuckLow(true) }, FaultDIOStuckLow},
+	}
+	for _, test := range tests {
+		sim := NewTM1638Sim()
+		test.setup(sim)
+		chip := NewTM1638(sim.Pins())
+		chip.Timing = TM1638Timing{}
+		err := chip.Probe()
+		if kind := faultKind(err); kind != test.fault {
+			t.Errorf("%s: got %v, expected fault %v", test.name, err, test.fault)
+		} else if test.fault == -1 && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	sim := NewTM1638Sim()
+	led8 := NewLED8KEY(sim.Pins())
+	led8.Timing = TM1638Timing{}
+	disp16 := NewDISP16KEY(sim.Pins())
+	disp16.Timing = TM1638Timing{}
+
+	// A wired button is fine. K1/SEG1 is on the DISP16KEY but not the LED8KEY.
+	sim.SetKey(LED8KEYKeys[3], true)
+	if err := led8.HealthCheck(); err != nil {
+		t.Errorf("LED8KEY with a button down: %v", err)
+	}
+	sim.ReleaseKeys()
+	sim.SetKey(KeyPosition{1, 1}, true)
+	if err := disp16.HealthCheck(); err != nil {
+		t.Errorf("DISP16KEY with a button down: %v", err)
+	}
+	if err := led8.HealthCheck(); faultKind(err) != FaultImpossibleKeys {
+		t.Errorf("LED8KEY with an unwired key down: got %v", err)
+	}
+
+	// K3 isn't wired on the DISP16KEY.
+	sim.ReleaseKeys()
+	sim.SetKey(KeyPosition{3, 4}, true)
+	if err := disp16.HealthCheck(); faultKind(err) != FaultImpossibleKeys {
+		t.Errorf("DISP16KEY with an unwired key down: got %v", err)
+	}
+
+	sim.ReleaseKeys()
+	sim.SetDisconnected(true)
+	if err := led8.HealthCheck(); faultKind(err) != FaultNoChip {
+		t.Errorf("LED8KEY disconnected: got %v", err)
+	}
+	sim.SetDisconnected(false)
+	sim.SetDIOStuckLow(true)
+	if err := disp16.HealthCheck(); faultKind(err) != FaultDIOStuckLow {
+		t.Errorf("DISP16KEY with DIO stuck low: got %v", err)
+	}
+	sim.SetDIOStuckLow(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := led8.HealthCheckContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled HealthCheckContext: got %v", err)
+	}
+	if err := led8.HealthCheckContext(context.Background()); err != nil {
+		t.Errorf("healthy again: %v", err)
+	}
+}
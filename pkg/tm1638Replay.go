@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Replay captured bus traffic into the simulated chip and check what it
+	ends up showing. Traffic can come from the Tracer (TraceRecords, or the
+	lines written by WriterTracer) or from a VCD file (our PinRecorder or a
+	logic analyzer).
+
+	  sim := pkg.NewTM1638Sim()
+	  err := sim.ReplayVCD(file, "STROBE", "CLK", "DIO")
+	  ...
+	  err = sim.Expect(expectedRAM, true, 7)
+*/
+
+// Feed traced transactions to the chip. Only the bytes sent matter. The
+// chip makes up its own key scanning data for reads.
+func (x *TM1638Sim) ReplayTrace(records []TraceRecord) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	for _, r := range records {
+		x.beginTransaction()
+		x.receive(r.Command)
+		if !r.Read {
+			for _, b := range r.Data {
+				x.receive(b)
+			}
+		}
+		x.endTransaction()
+	}
+}
+
+// Parse the lines written by WriterTracer back into TraceRecords. Only the
+// command and the data are recovered -- enough for ReplayTrace.
+func ParseTraceLines(r io.Reader) ([]TraceRecord, error) {
+	ret := []TraceRecord{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		// <timestamp> <kind> 0xNN...
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[2], "0x") {
+			return nil, fmt.Errorf("Line %d: not a trace line: '%s'", lineNumber, line)
+		}
+		cmd, err := strconv.ParseUint(strings.TrimRight(fields[2][2:], ":"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: bad command '%s'", lineNumber, fields[2])
+		}
+		data := []byte{}
+		if i := strings.Index(line, " (error:"); i >= 0 {
+			line = line[:i]
+		}
+		if fields[1] == "address" {
+			// address 0xC2 (2): 5B 00 4F
+			// address 0xC2 (2)      (nothing sent after the address)
+			rest := ""
+			if i := strings.Index(line, "):"); i >= 0 {
+				rest = line[i+2:]
+			} else if !strings.HasSuffix(line, ")") {
+				return nil, fmt.Errorf("Line %d: bad address line '%s'", lineNumber, line)
+			}
+			for _, h := range strings.Fields(rest) {
+				v, err := strconv.ParseUint(h, 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("Line %d: bad data byte '%s'", lineNumber, h)
+				}
+				data = append(data, byte(v))
+			}
+		}
+		// Read results (data commands) don't matter for replay
+		record := newTraceRecord(time.Time{}, append([]byte{byte(cmd)}, data...), nil, nil)
+		record.End = time.Time{}
+		ret = append(ret, record)
+	}
+	return ret, scanner.Err()
+}
+
+// Drive the chip's pins from a VCD file. The three names pick the signals
+// for STROBE, CLK and DIO. A value of 0 drives the line low. 1, z and x all
+// release it (the pull-up takes it high). Everything else in the file is
+// ignored, including the timestamps -- only the order of changes matters.
+func (x *TM1638Sim) ReplayVCD(r io.Reader, strobe string, clk string, dio string) error {
+	ids := map[string]*simPinState{}
+	found := map[string]bool{}
+	inDefinitions := true
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		for _, token := range strings.Fields(scanner.Text()) {
+			if inDefinitions {
+				// We handle "$var wire 1 <id> <name> $end" on one line
+				continue
+			}
+			if token[0] == '#' || token[0] == '$' {
+				continue // Timestamps and $dumpvars/$end
+			}
+			value := token[0]
+			state, ok := ids[token[1:]]
+			if !ok {
+				continue // Not one of ours
+			}
+			if value != '0' && value != '1' && value != 'z' && value != 'Z' && value != 'x' && value != 'X' {
+				return fmt.Errorf("Line %d: unsupported value '%s'", lineNumber, token)
+			}
+			x.lock.Lock()
+			state.isOutput = value == '0'
+			state.latch = false
+			x.update()
+			x.lock.Unlock()
+		}
+		if inDefinitions {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 5 && fields[0] == "$var" {
+				name := fields[4]
+				switch name {
+				case strobe:
+					ids[fields[3]] = &x.strobe
+				case clk:
+					ids[fields[3]] = &x.clk
+				case dio:
+					ids[fields[3]] = &x.dio
+				}
+				found[name] = true
+			}
+			if len(fields) > 0 && fields[0] == "$enddefinitions" {
+				inDefinitions = false
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, name := range []string{strobe, clk, dio} {
+		if !found[name] {
+			return fmt.Errorf("No signal named '%s' in the VCD file", name)
+		}
+	}
+	return nil
+}
+
+// Check the display RAM and display control against what we expect.
+// The error lists every difference.
+func (x *TM1638Sim) Expect(display [16]byte, enabled bool, pulseWidth int) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	diffs := []string{}
+	for i := range display {
+		if x.display[i] != display[i] {
+			diffs = append(diffs, fmt.Sprintf("RAM[%d] is 0x%02X, expected 0x%02X", i, x.display[i], display[i]))
+		}
+	}
+	if x.displayOn != enabled {
+		diffs = append(diffs, fmt.Sprintf("display on is %v, expected %v", x.displayOn, enabled))
+	}
+	if x.pulseWidth != pulseWidth {
+		diffs = append(diffs, fmt.Sprintf("pulse width is %d, expected %d", x.pulseWidth, pulseWidth))
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%s", strings.Join(diffs, "; "))
+	}
+	return nil
+}
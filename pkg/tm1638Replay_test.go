@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+	The testdata directory holds bus traffic captured from both boards: a
+	WriterTracer trace (NAME.trace) and a PinRecorder VCD file (NAME.vcd).
+	Replaying either one must give the known RAM image, and the driver must
+	still send exactly the traced bytes.
+
+	Run the tests with TM1638_UPDATE=1 to capture the files again.
+*/
+
+type replayFixture struct {
+	name string
+	ram  [16]byte
+	draw func(strobe, clk, dio CheckedGPIOPin, tracer Tracer)
+}
+
+var replayFixtures = []replayFixture{
+	{
+		// "3.14" with LEDs 1 and 3 on
+		"led8key",
+		[16]byte{
+			0xCF, 0x01, 0x06, 0x00, 0x66, 0x01, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		func(strobe, clk, dio CheckedGPIOPin, tracer Tracer) {
+			p := NewLED8KEY(strobe, clk, dio)
+			p.Timing = TM1638Timing{}
+			p.Tracer = tracer
+			p.ConfigureDisplay(true, 3)
+			p.WriteString("3.14")
+			p.SetLEDs([8]bool{true, false, true})
+		},
+	},
+	{
+		// "12" turned into one byte per segment (see disp16key.go)
+		"disp16key",
+		[16]byte{
+			0x40, 0x00, 0xC0, 0x00, 0x80, 0x00, 0x40, 0x00,
+			0x40, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00,
+		},
+		func(strobe, clk, dio CheckedGPIOPin, tracer Tracer) {
+			p := NewDISP16KEY(strobe, clk, dio)
+			p.Timing = TM1638Timing{}
+			p.Tracer = tracer
+			p.ConfigureDisplay(true, 3)
+			p.WriteString("12")
+		},
+	},
+}
+
+// Draw on a recorded, traced simulator. Returns the VCD and trace files.
+func captureReplayFixture(t *testing.T, f replayFixture) (vcd []byte, trace []byte) {
+	t.Helper()
+	sim := NewTM1638Sim()
+	strobe, clk, dio := sim.Pins()
+	rec := NewPinRecorder()
+	ring := NewRingTracer(64)
+	f.draw(rec.Wrap("STROBE", strobe), rec.Wrap("CLK", clk), rec.Wrap("DIO", dio), ring)
+	if err := sim.Expect(f.ram, true, 3); err != nil {
+		t.Fatalf("%s: %v", f.name, err)
+	}
+
+	var vcdBuf, traceBuf bytes.Buffer
+	if err := rec.WriteVCD(&vcdBuf); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ring.Records() {
+		WriterTracer{W: &traceBuf}.Trace(r)
+	}
+	return vcdBuf.Bytes(), traceBuf.Bytes()
+}
+
+func readReplayFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture (run with TM1638_UPDATE=1 to capture it): %v", err)
+	}
+	return data
+}
+
+// The commands and data of the traced transactions, one string each.
+func tracedBytes(t *testing.T, trace []byte) []string {
+	t.Helper()
+	records, err := ParseTraceLines(bytes.NewReader(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := []string{}
+	for _, r := range records {
+		ret = append(ret, string(append([]byte{r.Command}, r.Data...)))
+	}
+	return ret
+}
+
+func TestReplayVCD(t *testing.T) {
+	for _, f := range replayFixtures {
+		sim := NewTM1638Sim()
+		err := sim.ReplayVCD(bytes.NewReader(readReplayFixture(t, f.name+".vcd")), "STROBE", "CLK", "DIO")
+		if err == nil {
+			err = sim.Expect(f.ram, true, 3)
+		}
+		if err != nil {
+			t.Errorf("%s VCD replay: %v", f.name, err)
+		}
+	}
+}
+
+func TestReplayTrace(t *testing.T) {
+	for _, f := range replayFixtures {
+		records, err := ParseTraceLines(bytes.NewReader(readReplayFixture(t, f.name+".trace")))
+		if err != nil {
+			t.Fatalf("%s trace: %v", f.name, err)
+		}
+		sim := NewTM1638Sim()
+		sim.ReplayTrace(records)
+		if err := sim.Expect(f.ram, true, 3); err != nil {
+			t.Errorf("%s trace replay: %v", f.name, err)
+		}
+	}
+}
+
+// The driver still sends what it sent when the fixtures were captured, and
+// its own recordings replay to the same RAM.
+func TestReplayCapture(t *testing.T) {
+	for _, f := range replayFixtures {
+		vcd, trace := captureReplayFixture(t, f)
+		if os.Getenv("TM1638_UPDATE") != "" {
+			err := os.MkdirAll("testdata", 0755)
+			if err == nil {
+				err = os.WriteFile(filepath.Join("testdata", f.name+".vcd"), vcd, 0644)
+			}
+			if err == nil {
+				err = os.WriteFile(filepath.Join("testdata", f.name+".trace"), trace, 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		got := tracedBytes(t, trace)
+		want := tracedBytes(t, readReplayFixture(t, f.name+".trace"))
+		if len(got) != len(want) {
+			t.Errorf("%s sent %d transactions, expected %d", f.name, len(got), len(want))
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s transaction %d is % X, expected % X", f.name, i, got[i], want[i])
+			}
+		}
+
+		sim := NewTM1638Sim()
+		err := sim.ReplayVCD(bytes.NewReader(vcd), "STROBE", "CLK", "DIO")
+		if err == nil {
+			err = sim.Expect(f.ram, true, 3)
+		}
+		if err != nil {
+			t.Errorf("%s VCD replay of the capture: %v", f.name, err)
+		}
+	}
+}
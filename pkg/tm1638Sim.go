@@ -0,0 +1,295 @@
+package pkg
+
+import (
+	"math/rand"
+	"sync"
+)
+
+/*
+	A simulated TM1638 chip. It watches the three bus lines through the pins
+	it hands out and behaves like the real chip:
+
+	  - The chip samples DIO on the rising edge of CLK (low bit first)
+	  - The first byte after STROBE goes low is a command
+	  - After an address command, every byte is display data
+	  - After a read command, the chip drives DIO on each falling edge of CLK
+	    with the key scanning bytes (low bit first)
+*/
+
+type TM1638Sim struct {
+	lock sync.Mutex
+
+	// What the chip is showing
+	display    [16]byte
+	displayOn  bool
+	pulseWidth int
+	keys       KeyMatrix
+
+	// Data command state (persists between transactions)
+	autoIncrement bool
+
+	// The host side of the lines
+	strobe simPinState
+	clk    simPinState
+	dio    simPinState
+
+	// Transaction state
+	active     bool // STROBE is low
+	strobeLine bool
+	clkLine    bool
+	bitCount   int  // Bits into the current byte
+	current    byte // Byte being received
+	byteCount  int  // Bytes received in this transaction
+	command    byte // First byte of this transaction
+	address    int
+	reading    bool
+	scan       [4]byte // Key scanning data being sent
+	readBit    int     // Bits sent in read mode
+	chipDIO    bool    // The level the chip drives on DIO (true is released)
+
+	// Faults
+	disconnected bool // The chip ignores the bus
+	dioStuckLow  bool // DIO is shorted to ground
+}
+
+type simPinState struct {
+	isOutput bool
+	latch    bool
+}
+
+// Create a simulated chip with blank display RAM and the display off.
+func NewTM1638Sim() *TM1638Sim {
+	ret := &TM1638Sim{}
+	ret.autoIncrement = true
+	ret.strobeLine = true
+	ret.clkLine = true
+	ret.chipDIO = true
+	return ret
+}
+
+// The STROBE, CLK and DIO pins wired to this chip.
+func (x *TM1638Sim) Pins() (CheckedGPIOPin, CheckedGPIOPin, CheckedGPIOPin) {
+	return &simPin{x, &x.strobe}, &simPin{x, &x.clk}, &simPin{x, &x.dio}
+}
+
+// A copy of the 16 bytes of display RAM.
+func (x *TM1638Sim) Display() [16]byte {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.display
+}
+
+// The last display control settings.
+func (x *TM1638Sim) DisplayControl() (enabled bool, pulseWidth int) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.displayOn, x.pulseWidth
+}
+
+// Press or release a key in the scan matrix.
+func (x *TM1638Sim) SetKey(pos KeyPosition, pressed bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if pressed {
+		x.keys |= KeyMatrixOf(pos)
+	} else {
+		x.keys &^= KeyMatrixOf(pos)
+	}
+}
+
+// Release all keys.
+func (x *TM1638Sim) ReleaseKeys() {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.keys = 0
+}
+
+// The key scanning data the chip would report right now.
+func (x *TM1638Sim) ScanData() [4]byte {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.keys.ScanData()
+}
+
+// Pretend the chip is unplugged (or plug it back in). An unplugged chip
+// ignores the bus and never drives DIO.
+func (x *TM1638Sim) SetDisconnected(disconnected bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.disconnected = disconnected
+	x.chipDIO = true
+}
+
+// Pretend the chip lost power and came back: random display RAM, the
+// display off and the data command back to its default.
+func (x *TM1638Sim) PowerCycle() {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	for i := range x.display {
+		x.display[i] = byte(rand.Intn(256))
+	}
+	x.displayOn = false
+	x.pulseWidth = 0
+	x.autoIncrement = true
+}
+
+// Short DIO to ground (or remove the short).
+func (x *TM1638Sim) SetDIOStuckLow(stuck bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.dioStuckLow = stuck
+}
+
+// The level of a host-side line. A released line is pulled up.
+func (p simPinState) level() bool {
+	return !p.isOutput || p.latch
+}
+
+// The level on the DIO wire: low if either side pulls it low.
+func (x *TM1638Sim) dioLine() bool {
+	return x.dio.level() && x.chipDIO && !x.dioStuckLow
+}
+
+// Look for edges after any change on the host side.
+func (x *TM1638Sim) update() {
+	strobe := x.strobe.level()
+	clk := x.clk.level()
+
+	if strobe != x.strobeLine {
+		x.strobeLine = strobe
+		if !strobe {
+			x.beginTransaction()
+		} else {
+			x.endTransaction()
+		}
+	}
+
+	if clk != x.clkLine {
+		x.clkLine = clk
+		if x.active && !x.disconnected {
+			if clk {
+				x.risingEdge()
+			} else {
+				x.fallingEdge()
+			}
+		}
+	}
+}
+
+// STROBE went low
+func (x *TM1638Sim) beginTransaction() {
+	x.active = true
+	x.bitCount = 0
+	x.current = 0
+	x.byteCount = 0
+	x.reading = false
+}
+
+// STROBE went high
+func (x *TM1638Sim) endTransaction() {
+	x.active = false
+	x.reading = false
+	x.chipDIO = true
+}
+
+func (x *TM1638Sim) fallingEdge() {
+	if !x.reading {
+		return
+	}
+	// Put the next bit of key data on DIO
+	index := x.readBit / 8
+	x.chipDIO = true
+	if index < len(x.scan) {
+		x.chipDIO = x.scan[index]&(1<<uint(x.readBit%8)) != 0
+	}
+}
+
+func (x *TM1638Sim) risingEdge() {
+	if x.reading {
+		x.readBit++
+		x.chipDIO = true
+		return
+	}
+	if x.dioLine() {
+		x.current |= 1 << uint(x.bitCount)
+	}
+	x.bitCount++
+	if x.bitCount == 8 {
+		x.receive(x.current)
+		x.bitCount = 0
+		x.current = 0
+	}
+}
+
+// Handle one complete byte from the host.
+func (x *TM1638Sim) receive(value byte) {
+	x.byteCount++
+	if x.byteCount > 1 {
+		// Display data after an address command
+		if x.command&0b11_000000 == 0b11_000000 {
+			x.display[x.address] = value
+			if x.autoIncrement {
+				x.address = (x.address + 1) & 0x0F
+			}
+		}
+		return
+	}
+
+	x.command = value
+	switch value & 0b11_000000 {
+	case 0b01_000000:
+		// Data command
+		x.autoIncrement = value&0b1_000 == 0
+		if value&0b11 == 0b10 {
+			x.reading = true
+			x.readBit = 0
+			x.scan = x.keys.ScanData()
+		}
+	case 0b10_000000:
+		// Display control
+		x.displayOn = value&0b1_000 != 0
+		x.pulseWidth = int(value & 0b111)
+	case 0b11_000000:
+		// Address
+		x.address = int(value & 0x0F)
+	}
+}
+
+// A pin wired to the simulated chip.
+type simPin struct {
+	sim   *TM1638Sim
+	state *simPinState
+}
+
+func (x *simPin) Write(state bool) error {
+	x.sim.lock.Lock()
+	defer x.sim.lock.Unlock()
+	x.state.latch = state
+	x.sim.update()
+	return nil
+}
+
+func (x *simPin) Read() (bool, error) {
+	x.sim.lock.Lock()
+	defer x.sim.lock.Unlock()
+	if x.state == &x.sim.dio {
+		return x.sim.dioLine(), nil
+	}
+	return x.state.level(), nil
+}
+
+func (x *simPin) Input() error {
+	x.sim.lock.Lock()
+	defer x.sim.lock.Unlock()
+	x.state.isOutput = false
+	x.sim.update()
+	return nil
+}
+
+func (x *simPin) Output() error {
+	x.sim.lock.Lock()
+	defer x.sim.lock.Unlock()
+	x.state.isOutput = true
+	x.sim.update()
+	return nil
+}
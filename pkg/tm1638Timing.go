@@ -0,0 +1,72 @@
+package pkg
+
+import "time"
+
+/*
+	Bus timing from the TM1638 datasheet (section "Switching characteristics"):
+
+	  PW_CLK   clock pulse width         >= 400ns
+	  f_OSC    max clock frequency        1MHz
+	  t_SETUP  data setup time           >= 100ns
+	  t_HOLD   data hold time            >= 100ns
+	  t_CLK_STB  clock to strobe         >= 1us
+	  PW_STB   strobe pulse width        >= 1us
+	  t_WAIT   read command to read data >= 1us
+*/
+
+// Timing profile for the bit-banged bus.
+type TM1638Timing struct {
+	ClockHalfPeriod time.Duration // Time CLK spends low and high on each bit we send
+	StrobeSetup     time.Duration // STROBE low to the first clock
+	StrobeHold      time.Duration // Last clock to STROBE high, and STROBE high after
+	ReadWait        time.Duration // Read command to the first data bit (t_WAIT)
+	ReadSettle      time.Duration // CLK low to sampling DIO on reads (also the CLK low time)
+	BusyWait        bool          // Spin instead of time.Sleep for each delay
+}
+
+// Timing for the Raspberry Pi. time.Sleep on Linux sleeps for tens of
+// microseconds at best, so we spin.
+var TimingPi = TM1638Timing{
+	ClockHalfPeriod: time.Microsecond,
+	StrobeSetup:     time.Microsecond,
+	StrobeHold:      time.Microsecond,
+	ReadWait:        2 * time.Microsecond,
+	ReadSettle:      2 * time.Microsecond,
+	BusyWait:        true,
+}
+
+// Timing for the RP2040 under TinyGo.
+var TimingRP2040 = TM1638Timing{
+	ClockHalfPeriod: time.Microsecond,
+	StrobeSetup:     time.Microsecond,
+	StrobeHold:      time.Microsecond,
+	ReadWait:        time.Microsecond,
+	ReadSettle:      time.Microsecond,
+	BusyWait:        true,
+}
+
+// As fast as the datasheet allows. Only use this when the GPIO backend is
+// fast enough that its own latency doesn't matter.
+var TimingDatasheet = TM1638Timing{
+	ClockHalfPeriod: 500 * time.Nanosecond,
+	StrobeSetup:     time.Microsecond,
+	StrobeHold:      time.Microsecond,
+	ReadWait:        time.Microsecond,
+	ReadSettle:      500 * time.Nanosecond,
+	BusyWait:        true,
+}
+
+// Wait for the given time.
+func (t *TM1638Timing) delay(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if !t.BusyWait {
+		time.Sleep(d)
+		return
+	}
+	start := time.Now()
+	for time.Since(start) < d {
+		// Spin
+	}
+}
@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+// Every preset meets the datasheet minimums at the top of tm1638Timing.go.
+func TestTimingPresets(t *testing.T) {
+	const (
+		pwCLK   = 400 * time.Nanosecond
+		tOSC    = time.Microsecond // 1 / f_OSC
+		tSETUP  = 100 * time.Nanosecond
+		tCLKSTB = time.Microsecond
+		pwSTB   = time.Microsecond
+		tWAIT   = time.Microsecond
+	)
+	for name, timing := range map[string]TM1638Timing{
+		"TimingPi":        TimingPi,
+		"TimingRP2040":    TimingRP2040,
+		"TimingDatasheet": TimingDatasheet,
+	} {
+		checks := []struct {
+			what string
+			got  time.Duration
+			min  time.Duration
+		}{
+			{"CLK pulse width", timing.ClockHalfPeriod, pwCLK},
+			{"CLK period", 2 * timing.ClockHalfPeriod, tOSC},
+			{"CLK low on reads", timing.ReadSettle, pwCLK},
+			{"STROBE to the first clock", timing.StrobeSetup, tSETUP},
+			{"last clock to STROBE", timing.StrobeHold, tCLKSTB},
+			{"STROBE pulse width", timing.StrobeHold, pwSTB},
+			{"read wait", timing.ReadWait, tWAIT},
+		}
+		for _, c := range checks {
+			if c.got < c.min {
+				t.Errorf("%s %s is %v, the datasheet minimum is %v", name, c.what, c.got, c.min)
+			}
+		}
+		if !timing.BusyWait {
+			t.Errorf("%s sleeps, which is far too slow for microsecond delays", name)
+		}
+	}
+}
+
+func TestTimingDelay(t *testing.T) {
+	// The zero timing never waits, for any of its delays
+	zero := TM1638Timing{}
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		for _, d := range []time.Duration{zero.ClockHalfPeriod, zero.StrobeSetup, zero.StrobeHold, zero.ReadWait, zero.ReadSettle} {
+			zero.delay(d)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("zero timing took %v", elapsed)
+	}
+
+	for _, busy := range []bool{true, false} {
+		timing := TM1638Timing{BusyWait: busy}
+		start := time.Now()
+		timing.delay(2 * time.Millisecond)
+		if elapsed := time.Since(start); elapsed < 2*time.Millisecond {
+			t.Errorf("BusyWait %v waited %v for 2ms", busy, elapsed)
+		}
+	}
+}
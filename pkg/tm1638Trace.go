@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// The kind of command that started a transaction.
+type TraceCommandKind int
+
+const (
+	TraceDataCommand TraceCommandKind = iota
+	TraceDisplayControl
+	TraceAddressCommand
+	TraceUnknownCommand
+)
+
+func (k TraceCommandKind) String() string {
+	switch k {
+	case TraceDataCommand:
+		return "data"
+	case TraceDisplayControl:
+		return "display"
+	case TraceAddressCommand:
+		return "address"
+	}
+	return "unknown"
+}
+
+// One bus transaction: everything between STROBE going low and going
+// high again.
+type TraceRecord struct {
+	Start   time.Time
+	End     time.Time
+	Command byte // The first byte sent (0 if nothing was sent)
+	Kind    TraceCommandKind
+	Address int    // The starting address for address commands. -1 otherwise.
+	Data    []byte // Bytes sent after the command, or bytes read back
+	Read    bool   // Data holds key scanning data that was read
+	Err     error  // The error that ended the transaction, if any
+}
+
+// Decode the command byte and sort out the payload.
+func newTraceRecord(start time.Time, sent []byte, read []byte, err error) TraceRecord {
+	ret := TraceRecord{Start: start, End: time.Now(), Address: -1, Err: err}
+	if len(sent) == 0 {
+		ret.Kind = TraceUnknownCommand
+		return ret
+	}
+	ret.Command = sent[0]
+	switch ret.Command & 0b11_000000 {
+	case 0b01_000000:
+		ret.Kind = TraceDataCommand
+	case 0b10_000000:
+		ret.Kind = TraceDisplayControl
+	case 0b11_000000:
+		ret.Kind = TraceAddressCommand
+		ret.Address = int(ret.Command & 0x0F)
+	default:
+		ret.Kind = TraceUnknownCommand
+	}
+	if len(read) > 0 {
+		ret.Read = true
+		ret.Data = append([]byte{}, read...)
+	} else if len(sent) > 1 {
+		ret.Data = append([]byte{}, sent[1:]...)
+	}
+	return ret
+}
+
+// A human readable decode of the transaction, e.g.
+//
+//	display 0x8F: on, pulse width 7
+//	address 0xC2 (2): 5B 00 4F
+func (r TraceRecord) String() string {
+	var ret string
+	c := r.Command
+	switch r.Kind {
+	case TraceDataCommand:
+		mode := "write"
+		if c&0b11 == 0b10 {
+			mode = "read"
+		}
+		inc := "auto-increment"
+		if c&0b1_000 != 0 {
+			inc = "fixed address"
+		}
+		ret = fmt.Sprintf("data 0x%02X: %s, %s", c, mode, inc)
+		if c&0b1_00 != 0 {
+			ret += ", test mode"
+		}
+	case TraceDisplayControl:
+		on := "off"
+		if c&0b1_000 != 0 {
+			on = "on"
+		}
+		ret = fmt.Sprintf("display 0x%02X: %s, pulse width %d", c, on, c&0b111)
+	case TraceAddressCommand:
+		ret = fmt.Sprintf("address 0x%02X (%d)", c, r.Address)
+	default:
+		ret = fmt.Sprintf("unknown 0x%02X", c)
+	}
+	if len(r.Data) > 0 {
+		ret += fmt.Sprintf(": % X", r.Data)
+	}
+	if r.Err != nil {
+		ret += fmt.Sprintf(" (error: %v)", r.Err)
+	}
+	return ret
+}
+
+// Receives every bus transaction. Trace is called with the bus locked, so
+// it should be quick.
+type Tracer interface {
+	Trace(r TraceRecord)
+}
+
+// The bytes of the transaction in progress.
+type traceState struct {
+	start time.Time
+	sent  []byte
+	read  []byte
+}
+
+// Tracer that keeps the most recent transactions in memory.
+type RingTracer struct {
+	lock    sync.Mutex
+	records []TraceRecord
+	next    int
+	full    bool
+}
+
+// Keep the last size transactions.
+func NewRingTracer(size int) *RingTracer {
+	return &RingTracer{records: make([]TraceRecord, size)}
+}
+
+func (t *RingTracer) Trace(r TraceRecord) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.records) == 0 {
+		return
+	}
+	t.records[t.next] = r
+	t.next++
+	if t.next == len(t.records) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// The recorded transactions, oldest first.
+func (t *RingTracer) Records() []TraceRecord {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.full {
+		return append([]TraceRecord{}, t.records[:t.next]...)
+	}
+	return append(append([]TraceRecord{}, t.records[t.next:]...), t.records[:t.next]...)
+}
+
+// Forget all recorded transactions.
+func (t *RingTracer) Clear() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.next = 0
+	t.full = false
+}
+
+// Tracer that writes one line per transaction, e.g. to a file.
+type WriterTracer struct {
+	W io.Writer
+}
+
+func (t WriterTracer) Trace(r TraceRecord) {
+	fmt.Fprintf(t.W, "%s %s\n", r.Start.Format(time.RFC3339Nano), r)
+}
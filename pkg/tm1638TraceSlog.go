@@ -0,0 +1,33 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Tracer that logs each transaction to a slog logger.
+type SlogTracer struct {
+	Logger *slog.Logger
+	Level  slog.Level
+}
+
+func (t SlogTracer) Trace(r TraceRecord) {
+	attrs := []slog.Attr{
+		slog.String("kind", r.Kind.String()),
+		slog.String("command", fmt.Sprintf("0x%02X", r.Command)),
+		slog.Duration("duration", r.End.Sub(r.Start)),
+	}
+	if r.Address >= 0 {
+		attrs = append(attrs, slog.Int("address", r.Address))
+	}
+	if len(r.Data) > 0 {
+		attrs = append(attrs, slog.String("data", fmt.Sprintf("% X", r.Data)), slog.Bool("read", r.Read))
+	}
+	if r.Err != nil {
+		attrs = append(attrs, slog.Any("error", r.Err))
+	}
+	t.Logger.LogAttrs(context.Background(), t.Level, "tm1638", attrs...)
+}
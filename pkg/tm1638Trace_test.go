@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceRecordString(t *testing.T) {
+	tests := []struct {
+		sent []byte
+		want string
+	}{
+		{[]byte{0x40}, "data 0x40: write, auto-increment"},
+		{[]byte{0x8F}, "display 0x8F: on, pulse width 7"},
+		{[]byte{0xC2, 0x5B, 0x00, 0x4F}, "address 0xC2 (2): 5B 00 4F"},
+		{[]byte{0xC2}, "address 0xC2 (2)"},
+	}
+	for _, test := range tests {
+		got := newTraceRecord(time.Time{}, test.sent, nil, nil).String()
+		if got != test.want {
+			t.Errorf("% X traced as %q, expected %q", test.sent, got, test.want)
+		}
+	}
+}
+
+// WriterTracer lines parse back to the same commands and data.
+func TestTraceLinesRoundTrip(t *testing.T) {
+	sent := [][]byte{{0x40}, {0xC2, 0x5B, 0x00, 0x4F}, {0xC7}, {0x8B}}
+	var lines strings.Builder
+	for _, s := range sent {
+		WriterTracer{W: &lines}.Trace(newTraceRecord(time.Now(), s, nil, nil))
+	}
+	records, err := ParseTraceLines(strings.NewReader(lines.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(sent) {
+		t.Fatalf("parsed %d records from:\n%s", len(records), lines.String())
+	}
+	for i, r := range records {
+		got := append([]byte{r.Command}, r.Data...)
+		if string(got) != string(sent[i]) {
+			t.Errorf("record %d is % X, expected % X", i, got, sent[i])
+		}
+	}
+}
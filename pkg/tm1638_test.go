@@ -0,0 +1,458 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// A driver on a simulated chip with every transaction traced.
+func newTracedTM1638() (*TM1638, *TM1638Sim, *RingTracer) {
+	sim := NewTM1638Sim()
+	chip := NewTM1638(sim.Pins())
+	chip.Timing = TM1638Timing{}
+	tracer := NewRingTracer(16)
+	chip.Tracer = tracer
+	return chip, sim, tracer
+}
+
+// The command bytes of the traced transactions.
+func tracedCommands(tracer *RingTracer) []byte {
+	ret := []byte{}
+	for _, r := range tracer.Records() {
+		ret = append(ret, r.Command)
+	}
+	return ret
+}
+
+func TestWriteDataAfterFixedMode(t *testing.T) {
+	chip, sim, tracer := newTracedTM1638()
+	if err := chip.InitWriteData(false); err != nil {
+		t.Fatal(err)
+	}
+	// Two bytes need auto-increment. After that the chip stays in it.
+	if err := chip.WriteData(2, []byte{0x11, 0x22}); err != nil {
+		t.Fatal(err)
+	}
+	if err := chip.WriteData(4, []byte{0x33, 0x44}); err != nil {
+		t.Fatal(err)
+	}
+	got := tracedCommands(tracer)
+	want := []byte{0x48, 0x40, 0xC2, 0xC4}
+	if string(got) != string(want) {
+		t.Errorf("commands % X, expected % X", got, want)
+	}
+	if d := sim.Display(); d[2] != 0x11 || d[3] != 0x22 || d[4] != 0x33 || d[5] != 0x44 {
+		t.Errorf("display RAM % X", d)
+	}
+}
+
+// One byte goes in with the chip in either write mode.
+func TestWriteDataOneByteInFixedMode(t *testing.T) {
+	chip, sim, tracer := newTracedTM1638()
+	if err := chip.InitWriteData(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := chip.WriteData(7, []byte{0x77}); err != nil {
+		t.Fatal(err)
+	}
+	got := tracedCommands(tracer)
+	want := []byte{0x48, 0xC7}
+	if string(got) != string(want) {
+		t.Errorf("commands % X, expected % X", got, want)
+	}
+	if d := sim.Display(); d[7] != 0x77 {
+		t.Errorf("display RAM % X", d)
+	}
+}
+
+func TestWriteScattered(t *testing.T) {
+	tests := []struct {
+		name     string
+		init     []bool // InitWriteData calls first
+		writes   []AddressByte
+		commands []byte
+	}{
+		{"no mode yet", nil, []AddressByte{{1, 0x11}, {5, 0x55}}, []byte{0x48, 0xC1, 0xC5}},
+		{"after auto-increment", []bool{true}, []AddressByte{{1, 0x11}, {5, 0x55}}, []byte{0x40, 0xC1, 0xC5}},
+		{"after fixed", []bool{false}, []AddressByte{{1, 0x11}, {5, 0x55}}, []byte{0x48, 0xC1, 0xC5}},
+		{"a run", []bool{false}, []AddressByte{{3, 0x33}, {4, 0x44}, {5, 0x55}, {9, 0x99}}, []byte{0x48, 0x40, 0xC3, 0xC9}},
+		{"a run at the end", nil, []AddressByte{{0, 0x00}, {14, 0xEE}, {15, 0xFF}}, []byte{0x40, 0xC0, 0xCE}},
+	}
+	for _, test := range tests {
+		chip, sim, tracer := newTracedTM1638()
+		for _, auto := range test.init {
+			if err := chip.InitWriteData(auto); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := chip.WriteScattered(test.writes); err != nil {
+			t.Fatal(err)
+		}
+		got := tracedCommands(tracer)
+		if string(got) != string(test.commands) {
+			t.Errorf("%s: commands % X, expected % X", test.name, got, test.commands)
+		}
+		d := sim.Display()
+		for _, w := range test.writes {
+			if d[w.Address] != w.Data {
+				t.Errorf("%s: display RAM % X", test.name, d)
+				break
+			}
+		}
+	}
+}
+
+// The demo's pattern: InitWriteData(true) once, then board writes. None of
+// them needs another data command.
+func TestBoardWritesAfterInit(t *testing.T) {
+	sim := NewTM1638Sim()
+	p := NewLED8KEY(sim.Pins())
+	p.Timing = TM1638Timing{}
+	tracer := NewRingTracer(64)
+	p.Tracer = tracer
+	if err := p.InitWriteData(true); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.WriteString("12345678"); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetLEDs([8]bool{true, false, true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, cmd := range tracedCommands(tracer) {
+		if i > 0 && cmd&0b11_000000 == 0b01_000000 {
+			t.Errorf("data command % X sent as transaction %d", cmd, i)
+		}
+	}
+}
+
+func TestWriteDataWithoutInit(t *testing.T) {
+	chip, _, tracer := newTracedTM1638()
+	for i := 0; i < 2; i++ {
+		if err := chip.WriteData(0, []byte{0x3F}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only one data command: nothing to restore
+	got := tracedCommands(tracer)
+	want := []byte{0x40, 0xC0, 0xC0}
+	if string(got) != string(want) {
+		t.Errorf("commands % X, expected % X", got, want)
+	}
+}
+
+// A TM1638 that wasn't made by NewTM1638 must not hang on the bus lock.
+func TestZeroTM1638(t *testing.T) {
+	sim := NewTM1638Sim()
+	var chip TM1638
+	chip.STROBE, chip.CLK, chip.DIO = sim.Pins()
+	chip.STROBE.Write(true)
+	chip.STROBE.Output()
+	chip.CLK.Write(true)
+	chip.CLK.Output()
+	chip.DIO.Write(false)
+	chip.DIO.Input()
+	if err := chip.WriteData(0, []byte{0x06}); err != nil {
+		t.Fatal(err)
+	}
+	if d := sim.Display(); d[0] != 0x06 {
+		t.Errorf("display RAM % X", d)
+	}
+}
+
+var errPinFault = errors.New("pin fault")
+
+// A pin on the simulator that fails Write or Read once armed. It remembers
+// how the line was last left.
+type failingPin struct {
+	pin       CheckedGPIOPin
+	failWrite bool
+	failRead  bool
+	level     bool
+	output    bool
+}
+
+func (x *failingPin) Write(state bool) error {
+	if x.failWrite {
+		return errPinFault
+	}
+	x.level = state
+	return x.pin.Write(state)
+}
+
+func (x *failingPin) Read() (bool, error) {
+	if x.failRead {
+		return false, errPinFault
+	}
+	return x.pin.Read()
+}
+
+func (x *failingPin) Input() error {
+	x.output = false
+	return x.pin.Input()
+}
+
+func (x *failingPin) Output() error {
+	x.output = true
+	return x.pin.Output()
+}
+
+func newFailingPins() (strobe, clk, dio *failingPin) {
+	simStrobe, simCLK, simDIO := NewTM1638Sim().Pins()
+	return &failingPin{pin: simStrobe}, &failingPin{pin: simCLK}, &failingPin{pin: simDIO}
+}
+
+// After a failed transaction STROBE is back high and DIO isn't driven.
+func expectBusReleased(t *testing.T, strobe, dio *failingPin) {
+	t.Helper()
+	if !strobe.level || !strobe.output {
+		t.Errorf("STROBE left level=%v output=%v", strobe.level, strobe.output)
+	}
+	if dio.output {
+		t.Error("DIO left driven")
+	}
+}
+
+func TestWritePinErrorReachesCaller(t *testing.T) {
+	strobe, clk, dio := newFailingPins()
+	p, err := NewLED8KEYE(strobe, clk, dio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Timing = TM1638Timing{}
+
+	// The data command starts with a 0 bit, so DIO is driven low when the
+	// clock fails
+	clk.failWrite = true
+	if err := p.WriteString("8"); !errors.Is(err, errPinFault) {
+		t.Errorf("WriteString returned %v", err)
+	}
+	expectBusReleased(t, strobe, dio)
+
+	clk.failWrite = false
+	strobe.failWrite = true
+	if err := p.WriteData(0, []byte{0x7F}); !errors.Is(err, errPinFault) {
+		t.Errorf("WriteData returned %v", err)
+	}
+	strobe.failWrite = false
+	if err := p.WriteData(0, []byte{0x7F}); err != nil {
+		t.Errorf("WriteData after the fault: %v", err)
+	}
+}
+
+func TestReadPinErrorReachesCaller(t *testing.T) {
+	strobe, clk, dio := newFailingPins()
+	p, err := NewLED8KEYE(strobe, clk, dio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Timing = TM1638Timing{}
+
+	dio.failRead = true
+	var buttons [8]bool
+	if err := p.ReadButtons(&buttons); !errors.Is(err, errPinFault) {
+		t.Errorf("ReadButtons returned %v", err)
+	}
+	expectBusReleased(t, strobe, dio)
+}
+
+func TestSetupPinError(t *testing.T) {
+	strobe, clk, dio := newFailingPins()
+	clk.failWrite = true
+	if _, err := NewTM1638E(strobe, clk, dio); !errors.Is(err, errPinFault) {
+		t.Errorf("NewTM1638E returned %v", err)
+	}
+	if _, err := NewLED8KEYE(strobe, clk, dio); !errors.Is(err, errPinFault) {
+		t.Errorf("NewLED8KEYE returned %v", err)
+	}
+	if _, err := NewDISP16KEYE(strobe, clk, dio); !errors.Is(err, errPinFault) {
+		t.Errorf("NewDISP16KEYE returned %v", err)
+	}
+	p, err := NewAlnumBoardE(strobe, clk, dio)
+	if !errors.Is(err, errPinFault) {
+		t.Errorf("NewAlnumBoardE returned %v", err)
+	}
+	if len(p.Segments) != 14 {
+		t.Error("NewAlnumBoardE didn't set up the 14-segment font")
+	}
+}
+
+// STROBE on the simulator, counting open transactions. A second one opening
+// before the first closes means two transactions interleaved on the wire.
+// It yields as each transaction starts, so an unlocked bus would interleave
+// even on one CPU.
+type strobeMonitor struct {
+	CheckedGPIOPin
+	lock         sync.Mutex
+	open         int
+	overlaps     int
+	transactions int
+}
+
+func (x *strobeMonitor) Write(state bool) error {
+	x.lock.Lock()
+	if !state {
+		x.open++
+		x.transactions++
+		if x.open > 1 {
+			x.overlaps++
+		}
+	} else if x.open > 0 {
+		x.open--
+	}
+	x.lock.Unlock()
+	err := x.CheckedGPIOPin.Write(state)
+	if !state {
+		runtime.Gosched()
+	}
+	return err
+}
+
+// Writers, readers and grouped transactions on one TM1638 from many
+// goroutines. Run with -race.
+func TestConcurrentBus(t *testing.T) {
+	sim := NewTM1638Sim()
+	simStrobe, clk, dio := sim.Pins()
+	strobe := &strobeMonitor{CheckedGPIOPin: simStrobe}
+	chip := NewTM1638(strobe, clk, dio)
+	chip.Timing = TM1638Timing{}
+	chip.Tracer = NewRingTracer(16)
+
+	keys := KeyMatrixOf(KeyPosition{3, 1}) | KeyMatrixOf(KeyPosition{1, 6})
+	for _, pos := range keys.Keys() {
+		sim.SetKey(pos, true)
+	}
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				v := byte(w<<4 | i&0x0F)
+				if err := chip.WriteData(w*4, []byte{v, v, v, v}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				m, err := chip.ReadKeyMatrix()
+				if err == nil && m != keys {
+					err = fmt.Errorf("read keys %v, expected %v", m.Keys(), keys.Keys())
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			err := chip.Transaction(func(bus TM1638Bus) error {
+				err := bus.ConfigureDisplay(true, i&7)
+				if err == nil {
+					err = bus.WriteScattered([]AddressByte{{3, 0xA0}, {15, 0xB0}})
+				}
+				return err
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if strobe.overlaps != 0 {
+		t.Errorf("%d of %d transactions overlapped another", strobe.overlaps, strobe.transactions)
+	}
+	if d, f := sim.Display(), chip.Frame(); d != f {
+		t.Errorf("chip RAM % X, driver frame % X", d, f)
+	}
+	if enabled, pulseWidth := sim.DisplayControl(); !enabled || pulseWidth != (rounds-1)&7 {
+		t.Errorf("display control %v %d", enabled, pulseWidth)
+	}
+}
+
+// A cancelled context stops every operation before it touches the bus.
+func TestCancelledContext(t *testing.T) {
+	sim := NewTM1638Sim()
+	simStrobe, clk, dio := sim.Pins()
+	strobe := &strobeMonitor{CheckedGPIOPin: simStrobe}
+	p := NewLED8KEY(strobe, clk, dio)
+	p.Timing = TM1638Timing{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buttons [8]bool
+	ops := map[string]func() error{
+		"WriteData":        func() error { return p.WriteDataContext(ctx, 0, []byte{0xFF}) },
+		"WriteScattered":   func() error { return p.WriteScatteredContext(ctx, []AddressByte{{1, 0xFF}}) },
+		"ConfigureDisplay": func() error { return p.ConfigureDisplayContext(ctx, true, 7) },
+		"InitWriteData":    func() error { return p.InitWriteDataContext(ctx, false) },
+		"ReadScanningData": func() error { return p.ReadScanningDataContext(ctx, make([]byte, 4)) },
+		"WriteString":      func() error { return p.WriteStringContext(ctx, "88888888") },
+		"SetLEDs":          func() error { return p.SetLEDsContext(ctx, [8]bool{true}) },
+		"ReadButtons":      func() error { return p.ReadButtonsContext(ctx, &buttons) },
+		"Restore":          func() error { return p.RestoreContext(ctx) },
+		"Transaction": func() error {
+			return p.TransactionContext(ctx, func(bus TM1638Bus) error { return nil })
+		},
+	}
+	for name, op := range ops {
+		if err := op(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s returned %v", name, err)
+		}
+	}
+	if strobe.transactions != 0 {
+		t.Errorf("%d transactions started", strobe.transactions)
+	}
+	if d := sim.Display(); d != ([16]byte{}) {
+		t.Errorf("display RAM % X", d)
+	}
+	if enabled, _ := sim.DisplayControl(); enabled {
+		t.Error("display turned on")
+	}
+	if f := p.Frame(); f != ([16]byte{}) {
+		t.Errorf("frame % X", f)
+	}
+}
+
+// Cancelling while another goroutine holds the bus gives up the wait.
+func TestCancelWaitingForBus(t *testing.T) {
+	chip, sim, _ := newTracedTM1638()
+	ctx, cancel := context.WithCancel(context.Background())
+	err := chip.Transaction(func(bus TM1638Bus) error {
+		done := make(chan error)
+		go func() { done <- chip.WriteDataContext(ctx, 0, []byte{0xFF}) }()
+		cancel()
+		return <-done
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteDataContext returned %v", err)
+	}
+	if d := sim.Display(); d[0] != 0 {
+		t.Errorf("display RAM % X", d)
+	}
+}
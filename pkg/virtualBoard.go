@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A board with no chip behind it. It keeps the display frame, the display
+// settings and the button states in memory. Front ends (like Terminal)
+// draw it and press its buttons.
+type VirtualBoard struct {
+	SevenSegFont
+	Layout BoardLayout
+
+	lock       sync.Mutex
+	frame      [16]byte
+	enabled    bool
+	pulseWidth int
+	buttons    [16]bool
+}
+
+// Everything a front end needs to draw the board.
+type BoardState struct {
+	Layout     BoardLayout
+	Frame      [16]byte
+	Enabled    bool
+	PulseWidth int
+	Buttons    [16]bool // Only the first Layout.NumButtons() are used
+}
+
+// The digits (xgfedcba, left to right) in the frame.
+func (s BoardState) Digits() [8]byte {
+	return s.Layout.Digits(s.Frame)
+}
+
+// The LEDs (left to right) in the frame.
+func (s BoardState) LEDs() [8]bool {
+	return s.Layout.LEDs(s.Frame)
+}
+
+// Create a virtual board with the display off and blank.
+func NewVirtualBoard(layout BoardLayout) *VirtualBoard {
+	ret := &VirtualBoard{Layout: layout}
+	ret.ResetFont()
+	return ret
+}
+
+// A copy of the board's current state.
+func (x *VirtualBoard) State() BoardState {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return BoardState{x.Layout, x.frame, x.enabled, x.pulseWidth, x.buttons}
+}
+
+// Same as TM1638.ConfigureDisplay.
+func (x *VirtualBoard) ConfigureDisplay(enabled bool, pulseWidth int) error {
+	if pulseWidth < 0 || pulseWidth > 7 {
+		return fmt.Errorf("Invalid pulseWidth value: %d", pulseWidth)
+	}
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.enabled = enabled
+	x.pulseWidth = pulseWidth
+	return nil
+}
+
+// Write 8 display digits.
+// digits = array of raw bit patterns for each display
+func (x *VirtualBoard) WriteDigits(digits [8]byte) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.Layout.SetDigits(&x.frame, digits)
+	return nil
+}
+
+// Print the string to the display using the configured font mapping.
+func (x *VirtualBoard) WriteString(chars string) error {
+	var digits [8]byte
+	err := x.BuildDigits(chars, 8, digits[:])
+	if err != nil {
+		return err
+	}
+	return x.WriteDigits(digits)
+}
+
+// Replace the whole frame and display settings, e.g. to mirror a real board.
+func (x *VirtualBoard) SetFrame(frame [16]byte, enabled bool, pulseWidth int) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.frame = frame
+	x.enabled = enabled
+	x.pulseWidth = pulseWidth
+}
+
+// Press or release a button (numbered like ReadButtons).
+func (x *VirtualBoard) SetButton(button int, pressed bool) {
+	if button < 0 || button >= x.Layout.NumButtons() {
+		return
+	}
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.buttons[button] = pressed
+}
+
+// A virtual LED8KEY. Implements LED8KEYBoard.
+type VirtualLED8KEY struct {
+	*VirtualBoard
+}
+
+func NewVirtualLED8KEY() *VirtualLED8KEY {
+	return &VirtualLED8KEY{NewVirtualBoard(LayoutLED8KEY)}
+}
+
+// Set the status of the LEDs.
+// leds = slice of booleans left to right, true means on
+func (x *VirtualLED8KEY) SetLEDs(leds [8]bool) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	x.Layout.SetLEDs(&x.frame, leds)
+	return nil
+}
+
+// Read the 8 buttons
+// Returns an array of booleans from left to right, true means pressed
+func (x *VirtualLED8KEY) ReadButtons(buttons *[8]bool) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	copy(buttons[:], x.buttons[:8])
+	return nil
+}
+
+// A virtual DISP16KEY. Implements DISP16KEYBoard.
+type VirtualDISP16KEY struct {
+	*VirtualBoard
+}
+
+func NewVirtualDISP16KEY() *VirtualDISP16KEY {
+	return &VirtualDISP16KEY{NewVirtualBoard(LayoutDISP16KEY)}
+}
+
+// Read the 16 buttons
+// Fills in button booleans from left to right and top to bottom
+func (x *VirtualDISP16KEY) ReadButtons(buttons *[16]bool) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	*buttons = x.buttons
+	return nil
+}
@@ -0,0 +1,348 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+	Serves a VirtualBoard to web browsers. The page at "/" opens a WebSocket
+	at "/ws" and the server pushes the board state every time it changes,
+	along with the board drawn in SVG by BoardRenderer:
+
+	  {"layout":"LED8KEY","frame":[...16 bytes...],"enabled":true,
+	   "pulseWidth":7,"buttons":[...],"svg":"<svg ..."}
+
+	The page just shows the SVG, so it always looks like the snapshots.
+
+	"/board.svg" and "/board.png" are snapshots drawn by BoardRenderer.
+
+	The paths are relative to where the WebBoard is mounted, so it can share
+	a mux with other handlers:
+
+	  mux.Handle("/board/", pkg.NewWebBoard(v.VirtualBoard))
+
+	serves the page at "/board/" and the WebSocket at "/board/ws".
+
+	Clicking (or touching) a button on the page (the SVG circle with its
+	index in data-button) sends it back as a press and a release:
+
+	  {"button":3,"pressed":true}
+
+	To watch a real board, point a VirtualBoard at the chip with Mirror and
+	serve that.
+*/
+
+type WebBoard struct {
+	Board    *VirtualBoard
+	Interval time.Duration // How often to look for changes to push. DefaultWebBoardInterval if not positive.
+
+	lock      sync.Mutex
+	clients   map[chan BoardState]*websocket.Conn
+	stopWatch context.CancelFunc // Stops the watcher. Set while there are clients.
+}
+
+// The message pushed to the browser
+type webBoardState struct {
+	Layout     string `json:"layout"`
+	Frame      []int  `json:"frame"`
+	Enabled    bool   `json:"enabled"`
+	PulseWidth int    `json:"pulseWidth"`
+	Buttons    []bool `json:"buttons"`
+	SVG        string `json:"svg"`
+}
+
+// The message from the browser
+type webBoardPress struct {
+	Button  int  `json:"button"`
+	Pressed bool `json:"pressed"`
+}
+
+var webBoardUpgrader = websocket.Upgrader{
+	// Only same-origin pages may connect (the gorilla default)
+	ReadBufferSize:  256,
+	WriteBufferSize: 1024,
+}
+
+// How often a WebBoard (or Mirror) looks for changes if no interval is set.
+const DefaultWebBoardInterval = 30 * time.Millisecond
+
+// Create a web front end for the board. Mount it on a mux or use
+// ListenAndServe.
+func NewWebBoard(board *VirtualBoard) *WebBoard {
+	return &WebBoard{
+		Board:    board,
+		Interval: DefaultWebBoardInterval,
+		clients:  map[chan BoardState]*websocket.Conn{},
+	}
+}
+
+// Serve the page and the WebSocket. Only the last element of the path is
+// looked at, so the board works wherever it is mounted.
+func (w *WebBoard) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:] {
+	case "", "index.html":
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(rw, webBoardPage)
+	case "ws":
+		w.serveWebSocket(rw, r)
+	case "board.svg":
+		rw.Header().Set("Content-Type", "image/svg+xml")
+		BoardRenderer{}.SVG(rw, w.Board.State())
+	case "board.png":
+		rw.Header().Set("Content-Type", "image/png")
+		BoardRenderer{}.PNG(rw, w.Board.State())
+	default:
+		http.NotFound(rw, r)
+	}
+}
+
+// Serve on the address (like "localhost:8080") until the context is done.
+func (w *WebBoard) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return w.Serve(ctx, l)
+}
+
+// Serve on the listener until the context is done. Then the WebSockets are
+// closed too (with "going away"), so the pages know the server is gone.
+func (w *WebBoard) Serve(ctx context.Context, l net.Listener) error {
+	server := &http.Server{Handler: w}
+	go func() {
+		<-ctx.Done()
+		server.Close() // This doesn't know about the hijacked WebSockets
+		w.closeClients()
+	}()
+	err := server.Serve(l)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Push the board state to every client when it changes. This runs while
+// there are clients (see addClient).
+func (w *WebBoard) watch(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWebBoardInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *BoardState
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		state := w.Board.State()
+		if last != nil && *last == state {
+			continue
+		}
+		last = &state
+		w.lock.Lock()
+		for c := range w.clients {
+			// Drop a stale state the client hasn't picked up yet
+			select {
+			case <-c:
+			default:
+			}
+			c <- state
+		}
+		w.lock.Unlock()
+	}
+}
+
+func (w *WebBoard) serveWebSocket(rw http.ResponseWriter, r *http.Request) {
+	conn, err := webBoardUpgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		return // Upgrade has already replied
+	}
+	defer conn.Close()
+
+	updates := make(chan BoardState, 1)
+	updates <- w.Board.State()
+	w.addClient(updates, conn)
+	defer w.removeClient(updates)
+
+	// Presses from the browser
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var press webBoardPress
+			err := conn.ReadJSON(&press)
+			if err != nil {
+				return
+			}
+			w.Board.SetButton(press.Button, press.Pressed)
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case state := <-updates:
+			err := conn.WriteJSON(newWebBoardState(state))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Register a client for updates. The first client starts the watcher.
+func (w *WebBoard) addClient(updates chan BoardState, conn *websocket.Conn) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.clients == nil {
+		w.clients = map[chan BoardState]*websocket.Conn{}
+	}
+	w.clients[updates] = conn
+	if w.stopWatch == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.stopWatch = cancel
+		go w.watch(ctx)
+	}
+}
+
+// Forget a client. The last client to go stops the watcher.
+func (w *WebBoard) removeClient(updates chan BoardState) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.clients, updates)
+	if len(w.clients) == 0 && w.stopWatch != nil {
+		w.stopWatch()
+		w.stopWatch = nil
+	}
+}
+
+// Say goodbye to every client and close its connection. Each
+// serveWebSocket then sees the error and cleans up after itself.
+func (w *WebBoard) closeClients() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, conn := range w.clients {
+		// WriteControl and Close are safe alongside the client's own writes
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
+
+func newWebBoardState(state BoardState) webBoardState {
+	ret := webBoardState{
+		Layout:     state.Layout.String(),
+		Frame:      make([]int, len(state.Frame)),
+		Enabled:    state.Enabled,
+		PulseWidth: state.PulseWidth,
+		Buttons:    state.Buttons[:state.Layout.NumButtons()],
+	}
+	for i, b := range state.Frame {
+		ret.Frame[i] = int(b) // A []byte would marshal as base64
+	}
+	var svg strings.Builder
+	BoardRenderer{Scale: 2}.SVG(&svg, state)
+	ret.SVG = svg.String()
+	return ret
+}
+
+// Copy the chip's shadow frame and display settings into the virtual board
+// every interval (DefaultWebBoardInterval if not positive) until the context
+// is done. The chip's own driver keeps running as usual; this only watches
+// what it writes.
+func (x *VirtualBoard) Mirror(ctx context.Context, chip *TM1638, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWebBoardInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		enabled, pulseWidth, _ := chip.DisplayControl()
+		x.SetFrame(chip.Frame(), enabled, pulseWidth)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+const webBoardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>TM1638 board</title>
+<style>
+  body { background: #202020; color: #ccc; font-family: sans-serif; margin: 2em; }
+  #board svg { max-width: 100%; height: auto; }
+  #board .button { cursor: pointer; }
+  #status { margin-top: 1em; font-size: small; }
+</style>
+</head>
+<body>
+<div id="board"></div>
+<div id="status">Connecting...</div>
+<script>
+"use strict";
+const board = document.getElementById("board");
+const statusLine = document.getElementById("status");
+
+// The SVG comes from BoardRenderer with every push. Its buttons carry
+// their index in data-button.
+let held = -1; // The button being pressed
+function press(ev) {
+  const b = ev.target.closest("[data-button]");
+  if (!b) return;
+  ev.preventDefault();
+  held = Number(b.getAttribute("data-button"));
+  send({button: held, pressed: true});
+}
+function release() {
+  if (held < 0) return;
+  send({button: held, pressed: false});
+  held = -1;
+}
+board.addEventListener("mousedown", press);
+board.addEventListener("touchstart", press);
+document.addEventListener("mouseup", release);
+document.addEventListener("touchend", release);
+
+let ws = null;
+function send(msg) {
+  if (ws && ws.readyState === WebSocket.OPEN) ws.send(JSON.stringify(msg));
+}
+
+function connect() {
+  const proto = location.protocol === "https:" ? "wss://" : "ws://";
+  // Next to the page, wherever the board is mounted
+  const dir = location.pathname.substring(0, location.pathname.lastIndexOf("/") + 1);
+  ws = new WebSocket(proto + location.host + dir + "ws");
+  ws.onopen = () => { statusLine.textContent = "Connected"; };
+  ws.onmessage = (ev) => { board.innerHTML = JSON.parse(ev.data).svg; };
+  ws.onclose = () => {
+    statusLine.textContent = "Disconnected. Retrying...";
+    setTimeout(connect, 1000);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,129 @@
+//go:build !tinygo
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A WebBoard mounted on a mux (without Serve) still pushes updates and
+// takes presses, at paths relative to the mount point.
+func TestWebBoardMounted(t *testing.T) {
+	v := NewVirtualLED8KEY()
+	mux := http.NewServeMux()
+	mux.Handle("/board/", NewWebBoard(v.VirtualBoard))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/board/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("page: %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	resp, err = http.Get(server.URL + "/board/board.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("snapshot: %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/board/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var state webBoardState
+	if err := conn.ReadJSON(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Layout != "LED8KEY" || state.Enabled {
+		t.Errorf("first state %+v", state)
+	}
+	var svg bytes.Buffer
+	BoardRenderer{Scale: 2}.SVG(&svg, v.State())
+	if state.SVG != svg.String() {
+		t.Errorf("pushed SVG isn't the BoardRenderer's:\n%s", state.SVG)
+	}
+	if !strings.Contains(state.SVG, `data-button="7"`) || strings.Contains(state.SVG, `data-button="8"`) {
+		t.Errorf("pushed SVG doesn't mark the 8 buttons:\n%s", state.SVG)
+	}
+
+	v.ConfigureDisplay(true, 7)
+	if err := conn.ReadJSON(&state); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Enabled || state.PulseWidth != 7 {
+		t.Errorf("pushed state %+v", state)
+	}
+
+	if err := conn.WriteJSON(webBoardPress{Button: 2, Pressed: true}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !v.State().Buttons[2] {
+		if time.Now().After(deadline) {
+			t.Fatal("press did not reach the board")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Stopping Serve closes the WebSockets too, and the client is told why.
+func TestWebBoardServeClosesWebSockets(t *testing.T) {
+	v := NewVirtualDISP16KEY()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- NewWebBoard(v.VirtualBoard).Serve(ctx, l) }()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+l.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var state webBoardState
+	if err := conn.ReadJSON(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	for {
+		err = conn.ReadJSON(&state)
+		if err != nil {
+			break
+		}
+	}
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("client got %v, expected a going away close", err)
+	}
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Serve returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Serve didn't return")
+	}
+}
@@ -0,0 +1,204 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	Fonts for alphanumeric digits with 14 or 16 segments. A glyph has one
+	bit per segment, in the order of the segment names below (the first
+	name is bit 0). The decimal point is always bit 16 (WideSegDP), after
+	the bits of either set, so it stays on the same segment line when the
+	font changes. A glyph is too wide for a byte, so a board writes each
+	digit to more than one byte of display RAM (see alnumBoard.go).
+
+	14 segments (Segments14):
+
+	   ----a----
+	  |\   |   /|
+	  f h  i  j b
+	  |   \|/   |
+	   -g1- -g2-
+	  |   /|\   |
+	  e k  l  m c
+	  |/   |   \|
+	   ----d----  dp
+
+	16 segments (Segments16) split the top and bottom bars in two:
+
+	   -a1- -a2-
+	       ...
+	   -d1- -d2-  dp
+
+	Glyphs are built from space-separated segment names:
+
+	  MustWideSegments(Segments14, "a b c d e f j k") // 0 with a slash
+	  MustWideSegments(Segments14, "b c dp")          // !
+*/
+
+var Segments14 = []string{"a", "b", "c", "d", "e", "f", "g1", "g2", "h", "i", "j", "k", "l", "m"}
+
+var Segments16 = []string{"a1", "a2", "b", "c", "d1", "d2", "e", "f", "g1", "g2", "h", "i", "j", "k", "l", "m"}
+
+// One digit's segments. See Segments14 and Segments16 for the bits.
+type WideGlyph uint32
+
+// The decimal point, the same bit for both segment sets
+const WideSegDP WideGlyph = 1 << 16
+
+// Build a glyph from space-separated segment names in the segment set (or
+// "dp").
+func WideSegments(set []string, names string) (WideGlyph, error) {
+	var ret WideGlyph
+	for _, name := range strings.Fields(names) {
+		var bit WideGlyph
+		if name == "dp" {
+			bit = WideSegDP
+		}
+		for i, n := range set {
+			if n == name {
+				bit = 1 << uint(i)
+			}
+		}
+		if bit == 0 {
+			return 0, fmt.Errorf("Unknown segment \"%s\" in \"%s\"", name, names)
+		}
+		if ret&bit != 0 {
+			return 0, fmt.Errorf("Segment %s repeated in \"%s\"", name, names)
+		}
+		ret |= bit
+	}
+	return ret, nil
+}
+
+// WideSegments for names known to be good. Panics on a bad name.
+func MustWideSegments(set []string, names string) WideGlyph {
+	ret, err := WideSegments(set, names)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// The names of the glyph's lit segments in bit order, "dp" last.
+func WideSegmentNames(set []string, glyph WideGlyph) []string {
+	ret := []string{}
+	for i, name := range set {
+		if glyph&(1<<uint(i)) != 0 {
+			ret = append(ret, name)
+		}
+	}
+	if glyph&WideSegDP != 0 {
+		ret = append(ret, "dp")
+	}
+	return ret
+}
+
+// The bits a glyph may use with the segment set
+func wideSegmentMask(set []string) WideGlyph {
+	return WideSegDP | (1<<uint(len(set)) - 1)
+}
+
+// Turn a 14-segment glyph into the 16-segment one: a lights a1 and a2, d
+// lights d1 and d2.
+func Glyph14To16(glyph WideGlyph) WideGlyph {
+	var ret WideGlyph
+	for _, name := range WideSegmentNames(Segments14, glyph) {
+		switch name {
+		case "a":
+			ret |= MustWideSegments(Segments16, "a1 a2")
+		case "d":
+			ret |= MustWideSegments(Segments16, "d1 d2")
+		default:
+			ret |= MustWideSegments(Segments16, name)
+		}
+	}
+	return ret
+}
+
+// The printable ASCII characters for 14 segments
+var font14 = map[int]string{
+	' ': "", '!': "b c dp", '"': "i b", '#': "b c d g1 g2 i l",
+	'$': "a c d f g1 g2 i l", '%': "c f j k", '&': "a d e g1 h j m", '\'': "j",
+	'(': "j m", ')': "h k", '*': "g1 g2 h i j k l m", '+': "g1 g2 i l",
+	',': "k", '-': "g1 g2", '.': "dp", '/': "j k",
+
+	'0': "a b c d e f j k", '1': "b c j", '2': "a b d e g1 g2", '3': "a b c d g2",
+	'4': "b c f g1 g2", '5': "a c d f g1 g2", '6': "a c d e f g1 g2", '7': "a b c",
+	'8': "a b c d e f g1 g2", '9': "a b c d f g1 g2",
+
+	':': "i l", ';': "i k", '<': "j m", '=': "d g1 g2", '>': "h k", '?': "a b g2 l",
+	'@': "a b d e f g2 i",
+
+	'A': "a b c e f g1 g2", 'B': "a b c d g2 i l", 'C': "a d e f", 'D': "a b c d i l",
+	'E': "a d e f g1", 'F': "a e f g1", 'G': "a c d e f g2", 'H': "b c e f g1 g2",
+	'I': "a d i l", 'J': "b c d e", 'K': "e f g1 j m", 'L': "d e f",
+	'M': "b c e f h j", 'N': "b c e f h m", 'O': "a b c d e f", 'P': "a b e f g1 g2",
+	'Q': "a b c d e f m", 'R': "a b e f g1 g2 m", 'S': "a c d g2 h", 'T': "a i l",
+	'U': "b c d e f", 'V': "e f j k", 'W': "b c e f k m", 'X': "h j k m",
+	'Y': "h j l", 'Z': "a d j k",
+
+	'[': "a d e f", '\\': "h m", ']': "a b c d", '^': "k m", '_': "d", '`': "h",
+
+	'a': "d e g1 l", 'b': "c d e f g1 g2", 'c': "d e g1 g2", 'd': "b c d e g1 g2",
+	'e': "d e g1 k", 'f': "g1 g2 j l", 'g': "a b c d f g1 g2", 'h': "c e f g1 g2",
+	'i': "l", 'j': "c d", 'k': "i j l m", 'l': "e f",
+	'm': "c e g1 g2 l", 'n': "c e g1 g2", 'o': "c d e g1 g2", 'p': "a b e f g1 g2",
+	'q': "a b c f g1 g2", 'r': "e g1", 's': "a c d f g1 g2", 't': "d e f g1",
+	'u': "c d e", 'v': "e k", 'w': "c e k m", 'x': "h j k m",
+	'y': "b c d g2 i", 'z': "d g1 k",
+
+	'{': "a d g1 i l", '|': "i l", '}': "a d g2 i l", '~': "f h j",
+}
+
+type WideFont struct {
+	Segments []string // Segments14 or Segments16
+	font     map[int]WideGlyph
+}
+
+// The printable ASCII characters (space to '~') for 14 segments.
+func NewWideFont14() *WideFont {
+	ret := &WideFont{Segments: Segments14, font: map[int]WideGlyph{}}
+	for c, names := range font14 {
+		ret.font[c] = MustWideSegments(Segments14, names)
+	}
+	return ret
+}
+
+// The same characters as NewWideFont14 for 16 segments.
+func NewWideFont16() *WideFont {
+	ret := &WideFont{Segments: Segments16, font: map[int]WideGlyph{}}
+	for c, glyph := range NewWideFont14().font {
+		ret.font[c] = Glyph14To16(glyph)
+	}
+	return ret
+}
+
+// Get the font mapping. Mutate this map as needed.
+func (x *WideFont) GetMutableFont() map[int]WideGlyph {
+	return x.font
+}
+
+// Build the glyphs for the text, like SevenSegFont.BuildDigits. Decimal
+// points are merged into the character before them. Unused glyphs are
+// blanked.
+func (x *WideFont) BuildGlyphs(chars string, outGlyphs []WideGlyph) error {
+	return buildGlyphs(x.font, WideSegDP, chars, outGlyphs)
+}
+
+// All the characters in the font with exactly this glyph, lowest first.
+func (x *WideFont) Candidates(glyph WideGlyph) []int {
+	return fontCandidates(x.font, glyph)
+}
+
+// Find the character for a glyph, like SevenSegFont.DecodeDigit.
+func (x *WideFont) DecodeGlyph(glyph WideGlyph) (char int, dot bool, exact bool) {
+	return decodeGlyph(x.font, WideSegDP, glyph)
+}
+
+// Turn glyphs back into text, like SevenSegFont.DecodeDigits. Glyphs with
+// no exact match become '?'.
+func (x *WideFont) DecodeGlyphs(glyphs []WideGlyph) string {
+	return decodeGlyphs(x.font, WideSegDP, glyphs)
+}
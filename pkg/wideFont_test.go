@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func expectSameWideFont(t *testing.T, got *WideFont, want *WideFont) {
+	t.Helper()
+	if len(got.Segments) != len(want.Segments) {
+		t.Errorf("font has %d segments, expected %d", len(got.Segments), len(want.Segments))
+	}
+	if len(got.font) != len(want.font) {
+		t.Fatalf("font has %d glyphs, expected %d", len(got.font), len(want.font))
+	}
+	for c, g := range want.font {
+		if got.font[c] != g {
+			t.Errorf("glyph '%c' is %v, expected %v", c, WideSegmentNames(got.Segments, got.font[c]), WideSegmentNames(want.Segments, g))
+		}
+	}
+}
+
+func TestWideFontTable(t *testing.T) {
+	for _, font := range []*WideFont{NewWideFont14(), NewWideFont16()} {
+		if err := font.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		for c := ' '; c <= '~'; c++ {
+			g, exist := font.font[int(c)]
+			if !exist {
+				t.Errorf("%d segments: no glyph for '%c'", len(font.Segments), c)
+			}
+			hasDP := c == '.' || c == '!'
+			if (g&WideSegDP != 0) != hasDP {
+				t.Errorf("%d segments: '%c' has the wrong point: %v", len(font.Segments), c, WideSegmentNames(font.Segments, g))
+			}
+		}
+		if len(font.font) != '~'-' '+1 {
+			t.Errorf("%d segments: %d glyphs", len(font.Segments), len(font.font))
+		}
+	}
+
+	font14, font16 := NewWideFont14(), NewWideFont16()
+	tests := []struct {
+		font  *WideFont
+		c     int
+		names string
+		bits  WideGlyph
+	}{
+		{font14, '0', "a b c d e f j k", 0x0C3F},
+		{font14, 'T', "a i l", 0x1201},
+		{font14, '.', "dp", 0x10000},
+		{font16, '0', "a1 a2 b c d1 d2 e f j k", 0x30FF},
+		{font16, 'T', "a1 a2 i l", 0x4803},
+		{font16, '!', "b c dp", 0x1000C},
+	}
+	for _, test := range tests {
+		g := test.font.font[test.c]
+		if g != test.bits || g != MustWideSegments(test.font.Segments, test.names) {
+			t.Errorf("%d segments: '%c' is %05X %v, expected %05X (%s)", len(test.font.Segments), test.c, g, WideSegmentNames(test.font.Segments, g), test.bits, test.names)
+		}
+	}
+}
+
+func TestWideSegments(t *testing.T) {
+	if _, err := WideSegments(Segments14, "a a1"); err == nil {
+		t.Error("no error for a 16-segment name in a 14-segment glyph")
+	}
+	if _, err := WideSegments(Segments16, "dp b dp"); err == nil {
+		t.Error("no error for a repeated dp")
+	}
+	g := MustWideSegments(Segments14, "dp g1 a")
+	if names := WideSegmentNames(Segments14, g); len(names) != 3 || names[0] != "a" || names[2] != "dp" {
+		t.Errorf("names %v", names)
+	}
+}
+
+func TestWideFontBuildDecode(t *testing.T) {
+	font := NewWideFont14()
+	var glyphs [4]WideGlyph
+	if err := font.BuildGlyphs("Hi.2!", glyphs[:]); err != nil {
+		t.Fatal(err)
+	}
+	want := [4]WideGlyph{font.font['H'], font.font['i'] | WideSegDP, font.font['2'], font.font['!']}
+	if glyphs != want {
+		t.Errorf("glyphs % X, expected % X", glyphs, want)
+	}
+	if got := font.DecodeGlyphs(glyphs[:]); got != "Hi.2!" {
+		t.Errorf("decoded as %q", got)
+	}
+	if err := font.BuildGlyphs("HELLO", glyphs[:]); err == nil {
+		t.Error("no error for 5 characters on 4 digits")
+	}
+
+	// One segment more is the nearest character, but not exact
+	c, dot, exact := font.DecodeGlyph(font.font['E'] | MustWideSegments(Segments14, "m dp"))
+	if c != 'E' || !dot || exact {
+		t.Errorf("decoded as '%c', %v, %v", c, dot, exact)
+	}
+}
+
+func TestWideFontTextRoundTrip(t *testing.T) {
+	for _, want := range []*WideFont{NewWideFont14(), NewWideFont16()} {
+		var text bytes.Buffer
+		if err := want.Save(&text); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadWideFont(&text, want.Segments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectSameWideFont(t, got, want)
+	}
+
+	if _, err := LoadWideFont(bytes.NewBufferString("A a1 a2\n"), Segments14); err == nil {
+		t.Error("no error for 16-segment names in a 14-segment font")
+	}
+}
+
+func TestWideFontJSONRoundTrip(t *testing.T) {
+	for _, want := range []*WideFont{NewWideFont14(), NewWideFont16()} {
+		data, err := json.Marshal(WideFontFile{Font: want})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := WideFontFile{Font: &WideFont{Segments: want.Segments}}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		expectSameWideFont(t, got.Font, want)
+	}
+}
+
+func TestLoadWideFontFile(t *testing.T) {
+	dir := t.TempDir()
+	want := NewWideFont16()
+
+	data, err := json.Marshal(WideFontFile{Font: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "font.json")
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	if err := want.Save(&text); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "font.txt")
+	if err := os.WriteFile(textPath, text.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{jsonPath, textPath} {
+		got, err := LoadWideFontFile(path, Segments16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectSameWideFont(t, got, want)
+		if _, err := LoadWideFontFile(path, Segments14); err == nil {
+			t.Errorf("%s loaded as a 14-segment font", path)
+		}
+	}
+}
+
+func TestWideFontRegistry(t *testing.T) {
+	font := NewWideFont14()
+	if err := font.UseFont("default16"); err != nil {
+		t.Fatal(err)
+	}
+	expectSameWideFont(t, font, NewWideFont16())
+
+	custom := NewWideFont14()
+	custom.GetMutableFont()['0'] = MustWideSegments(Segments14, "a b c d e f")
+	if err := RegisterWideFont("test-plain-zero", custom); err != nil {
+		t.Fatal(err)
+	}
+	custom.GetMutableFont()['0'] = 0 // The registry has its own copy
+	if err := font.UseFont("test-plain-zero"); err != nil {
+		t.Fatal(err)
+	}
+	if font.font['0'] != MustWideSegments(Segments14, "a b c d e f") || len(font.Segments) != 14 {
+		t.Errorf("'0' is %v", WideSegmentNames(font.Segments, font.font['0']))
+	}
+
+	if err := font.UseFont("no-such-font"); err == nil {
+		t.Error("no error for an unknown font")
+	}
+	if err := RegisterWideFont("empty", &WideFont{Segments: Segments14}); err == nil {
+		t.Error("registered an empty font")
+	}
+	found := false
+	for _, name := range WideFontNames() {
+		found = found || name == "test-plain-zero"
+	}
+	if !found {
+		t.Errorf("names %v", WideFontNames())
+	}
+}